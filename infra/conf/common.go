@@ -0,0 +1,10 @@
+package conf
+
+import "google.golang.org/protobuf/proto"
+
+// Buildable is implemented by every JSON (or URL) config struct in this
+// package that can be turned into the protobuf Config consumed by a
+// proxy/* outbound or inbound.
+type Buildable interface {
+	Build() (proto.Message, error)
+}
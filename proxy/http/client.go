@@ -7,11 +7,13 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	stdnet "net"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/http2"
 
@@ -19,34 +21,380 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
 	"github.com/v2fly/v2ray-core/v5/common/bytespool"
+	"github.com/v2fly/v2ray-core/v5/common/dice"
 	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/net/cnc"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/common/retry"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/common/signal"
 	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
 	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/proxy"
 	"github.com/v2fly/v2ray-core/v5/transport"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
 )
 
 type Client struct {
 	serverPicker  protocol.ServerPicker
+	scheduler     *serverScheduler
 	policyManager policy.Manager
+	chainTag      string
+	rewriteRules  []*RewriteRule
+	udpMode       ClientConfig_UdpMode
+}
+
+const defaultFailureCooldown = 10 * time.Second
+
+// serverState is the mutable per-server scheduling state shared by every
+// ServerPicker strategy: a failure cooldown (used by all of them), a CONNECT
+// handshake latency EWMA (LeastLatency) and an in-flight Process count
+// (LeastConcurrency).
+type serverState struct {
+	mu           sync.Mutex
+	latencyEWMA  time.Duration
+	hasLatency   bool
+	coolingUntil time.Time
+
+	concurrency int64
+}
+
+// latencyEWMAWeight is the smoothing factor applied to each new handshake
+// latency sample; lower weights react more slowly to spikes.
+const latencyEWMAWeight = 0.2
+
+func (s *serverState) updateLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hasLatency {
+		s.latencyEWMA = d
+		s.hasLatency = true
+		return
+	}
+	s.latencyEWMA = time.Duration(latencyEWMAWeight*float64(d) + (1-latencyEWMAWeight)*float64(s.latencyEWMA))
+}
+
+func (s *serverState) latency() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latencyEWMA, s.hasLatency
+}
+
+func (s *serverState) isCooling(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Before(s.coolingUntil)
+}
+
+func (s *serverState) markFailure(now time.Time, cooldown time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.coolingUntil = now.Add(cooldown)
+}
+
+func (s *serverState) addConcurrency(delta int64) {
+	atomic.AddInt64(&s.concurrency, delta)
+}
+
+func (s *serverState) loadConcurrency() int64 {
+	return atomic.LoadInt64(&s.concurrency)
+}
+
+// serverScheduler holds the per-server state (cooldown, latency, concurrency,
+// weight) shared across whichever ServerPicker strategy NewClient selects.
+// It is built once in NewClient from the server list it will pick from, so
+// every *protocol.ServerSpec pointer used as a map key remains stable and
+// reused for the Client's lifetime.
+type serverScheduler struct {
+	servers  []*protocol.ServerSpec
+	states   map[*protocol.ServerSpec]*serverState
+	weights  map[*protocol.ServerSpec]uint32
+	cooldown time.Duration
+}
+
+func newServerScheduler(serverList *protocol.ServerList, weights []uint32, cooldown time.Duration) *serverScheduler {
+	if cooldown <= 0 {
+		cooldown = defaultFailureCooldown
+	}
+	size := serverList.Size()
+	sched := &serverScheduler{
+		servers:  make([]*protocol.ServerSpec, 0, size),
+		states:   make(map[*protocol.ServerSpec]*serverState, size),
+		weights:  make(map[*protocol.ServerSpec]uint32, size),
+		cooldown: cooldown,
+	}
+	for i := uint32(0); i < size; i++ {
+		server := serverList.GetServer(i)
+		sched.servers = append(sched.servers, server)
+		sched.states[server] = &serverState{}
+
+		weight := uint32(1)
+		if int(i) < len(weights) && weights[i] > 0 {
+			weight = weights[i]
+		}
+		sched.weights[server] = weight
+	}
+	return sched
+}
+
+// available returns every server not currently cooling down, or the full
+// server list if all of them are, so a fully unhealthy pool still fails over
+// to something instead of refusing to pick a server at all.
+func (s *serverScheduler) available() []*protocol.ServerSpec {
+	now := time.Now()
+	out := make([]*protocol.ServerSpec, 0, len(s.servers))
+	for _, server := range s.servers {
+		if !s.states[server].isCooling(now) {
+			out = append(out, server)
+		}
+	}
+	if len(out) == 0 {
+		return s.servers
+	}
+	return out
+}
+
+func (s *serverScheduler) state(server *protocol.ServerSpec) *serverState {
+	return s.states[server]
+}
+
+func (s *serverScheduler) markFailure(server *protocol.ServerSpec) {
+	s.states[server].markFailure(time.Now(), s.cooldown)
+}
+
+// roundRobinServerPicker cycles through the scheduler's currently-available
+// servers in order.
+type roundRobinServerPicker struct {
+	sched *serverScheduler
+	next  uint32
+}
+
+func (p *roundRobinServerPicker) PickServer() *protocol.ServerSpec {
+	candidates := p.sched.available()
+	idx := atomic.AddUint32(&p.next, 1) - 1
+	return candidates[idx%uint32(len(candidates))]
+}
+
+// randomServerPicker picks uniformly at random among available servers.
+type randomServerPicker struct {
+	sched *serverScheduler
+}
+
+func (p *randomServerPicker) PickServer() *protocol.ServerSpec {
+	candidates := p.sched.available()
+	return candidates[dice.Roll(len(candidates))]
+}
+
+// leastLatencyServerPicker picks the available server with the lowest
+// CONNECT handshake latency EWMA, trying every server at least once before
+// ranking by latency.
+type leastLatencyServerPicker struct {
+	sched *serverScheduler
+}
+
+func (p *leastLatencyServerPicker) PickServer() *protocol.ServerSpec {
+	candidates := p.sched.available()
+	var best *protocol.ServerSpec
+	var bestLatency time.Duration
+	for _, server := range candidates {
+		latency, known := p.sched.state(server).latency()
+		if !known {
+			return server
+		}
+		if best == nil || latency < bestLatency {
+			best, bestLatency = server, latency
+		}
+	}
+	return best
+}
+
+// leastConcurrencyServerPicker picks the available server with the fewest
+// in-flight Process calls.
+type leastConcurrencyServerPicker struct {
+	sched *serverScheduler
+}
+
+func (p *leastConcurrencyServerPicker) PickServer() *protocol.ServerSpec {
+	candidates := p.sched.available()
+	best := candidates[0]
+	bestLoad := p.sched.state(best).loadConcurrency()
+	for _, server := range candidates[1:] {
+		if load := p.sched.state(server).loadConcurrency(); load < bestLoad {
+			best, bestLoad = server, load
+		}
+	}
+	return best
+}
+
+// weightedRandomServerPicker picks randomly among available servers,
+// weighted by the ClientConfig.ServerWeights entry each was built with.
+type weightedRandomServerPicker struct {
+	sched *serverScheduler
+}
+
+func (p *weightedRandomServerPicker) PickServer() *protocol.ServerSpec {
+	candidates := p.sched.available()
+	var total uint32
+	for _, server := range candidates {
+		total += p.sched.weights[server]
+	}
+	if total == 0 {
+		return candidates[dice.Roll(len(candidates))]
+	}
+	r := uint32(dice.Roll(int(total)))
+	var cum uint32
+	for _, server := range candidates {
+		cum += p.sched.weights[server]
+		if r < cum {
+			return server
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func newServerPicker(strategy ClientConfig_Strategy, sched *serverScheduler) protocol.ServerPicker {
+	switch strategy {
+	case ClientConfig_RANDOM:
+		return &randomServerPicker{sched: sched}
+	case ClientConfig_LEAST_LATENCY:
+		return &leastLatencyServerPicker{sched: sched}
+	case ClientConfig_LEAST_CONCURRENCY:
+		return &leastConcurrencyServerPicker{sched: sched}
+	case ClientConfig_WEIGHTED_RANDOM:
+		return &weightedRandomServerPicker{sched: sched}
+	default:
+		return &roundRobinServerPicker{sched: sched}
+	}
 }
 
 type h2Conn struct {
-	rawConn net.Conn
-	h2Conn  *http2.ClientConn
+	rawConn    net.Conn
+	h2Conn     *http2.ClientConn
+	createdAt  time.Time
+	lastUsedAt time.Time
 }
 
+// h2PoolKey scopes a pooled h2 connection to both the dialed destination and
+// the ChainTag it was dialed through. Two outbounds that share a dest but
+// differ in ChainTag (e.g. one dialing directly, one via an ssh detour) reach
+// that dest over different network paths and must never share a connection,
+// the same class of bug fixed for the SSH transport's pool in poolKey.
+type h2PoolKey struct {
+	dest     net.Destination
+	chainTag string
+}
+
+const (
+	defaultH2PoolPingInterval = 30 * time.Second
+	defaultH2PoolMaxIdle      = 5 * time.Minute
+	h2PoolPingTimeout         = 5 * time.Second
+)
+
 var (
 	cachedH2Mutex sync.Mutex
-	cachedH2Conns map[net.Destination]h2Conn
+	cachedH2Conns map[h2PoolKey]*h2Conn
+
+	// h2PoolConfigOnce applies the first Client's pool tunables to the
+	// (package-wide, dest+chainTag-keyed) h2 connection pool. The pool is
+	// shared across every Client regardless of which outbound created it,
+	// so later configs cannot override an already-running janitor.
+	h2PoolConfigOnce sync.Once
+	h2PoolJanitorRun sync.Once
+	h2PoolInterval   = defaultH2PoolPingInterval
+	h2PoolMaxIdle    = defaultH2PoolMaxIdle
+	h2PoolMaxLife    time.Duration
 )
 
+// statsCounter returns the named stats.Counter if a stats.Manager feature is
+// available, or nil otherwise. Counting is best-effort: callers must treat a
+// nil result as "no-op".
+func statsCounter(ctx context.Context, name string) stats.Counter {
+	v := core.FromContext(ctx)
+	if v == nil {
+		return nil
+	}
+	manager, ok := v.GetFeature(stats.ManagerType()).(stats.Manager)
+	if !ok {
+		return nil
+	}
+	counter, err := stats.GetOrRegisterCounter(manager, name)
+	if err != nil {
+		return nil
+	}
+	return counter
+}
+
+// startH2PoolJanitor lazily starts the single background goroutine that
+// periodically PINGs every cached h2 connection, evicting ones that fail to
+// respond or have exceeded the configured idle/lifetime thresholds. The
+// goroutine outlives whichever Process call happens to trigger it, so it
+// must not inherit that call's (soon to be cancelled) context: doing so
+// would make every later context.WithTimeout derive from an already-done
+// parent, and every PING would fail instantly, evicting every cached
+// connection rather than just the unhealthy ones. Instead it keeps only the
+// core.Instance (for stats lookups) and re-homes it on a background context.
+func startH2PoolJanitor(ctx context.Context) {
+	h2PoolJanitorRun.Do(func() {
+		v := core.MustFromContext(ctx)
+		janitorCtx := core.NewContext(context.Background(), v)
+		go h2PoolJanitorLoop(janitorCtx)
+	})
+}
+
+func h2PoolJanitorLoop(ctx context.Context) {
+	ticker := time.NewTicker(h2PoolInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		evictDeadH2Conns(ctx)
+	}
+}
+
+func evictDeadH2Conns(ctx context.Context) {
+	cachedH2Mutex.Lock()
+	snapshot := make(map[h2PoolKey]*h2Conn, len(cachedH2Conns))
+	for key, conn := range cachedH2Conns {
+		snapshot[key] = conn
+	}
+	cachedH2Mutex.Unlock()
+
+	now := time.Now()
+	for key, conn := range snapshot {
+		dead := false
+		switch {
+		case h2PoolMaxLife > 0 && now.Sub(conn.createdAt) > h2PoolMaxLife:
+			dead = true
+		case h2PoolMaxIdle > 0 && now.Sub(conn.lastUsedAt) > h2PoolMaxIdle:
+			dead = true
+		case !conn.h2Conn.CanTakeNewRequest():
+			dead = true
+		default:
+			pingCtx, cancel := context.WithTimeout(ctx, h2PoolPingTimeout)
+			err := conn.h2Conn.Ping(pingCtx)
+			cancel()
+			dead = err != nil
+		}
+
+		if !dead {
+			continue
+		}
+
+		cachedH2Mutex.Lock()
+		if cur, found := cachedH2Conns[key]; found && cur == conn {
+			delete(cachedH2Conns, key)
+		}
+		cachedH2Mutex.Unlock()
+
+		conn.rawConn.Close()
+		if c := statsCounter(ctx, "http>>>h2pool>>>evict"); c != nil {
+			c.Add(1)
+		}
+	}
+}
+
 // NewClient create a new http client based on the given config.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	serverList := protocol.NewServerList()
@@ -61,10 +409,32 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 		return nil, newError("0 target server")
 	}
 
+	h2PoolConfigOnce.Do(func() {
+		if config.H2PoolPingIntervalSec > 0 {
+			h2PoolInterval = time.Duration(config.H2PoolPingIntervalSec) * time.Second
+		}
+		if config.H2PoolMaxIdleSec > 0 {
+			h2PoolMaxIdle = time.Duration(config.H2PoolMaxIdleSec) * time.Second
+		}
+		if config.H2PoolMaxLifetimeSec > 0 {
+			h2PoolMaxLife = time.Duration(config.H2PoolMaxLifetimeSec) * time.Second
+		}
+	})
+
+	var cooldown time.Duration
+	if config.FailureCooldownSec > 0 {
+		cooldown = time.Duration(config.FailureCooldownSec) * time.Second
+	}
+	scheduler := newServerScheduler(serverList, config.ServerWeights, cooldown)
+
 	v := core.MustFromContext(ctx)
 	return &Client{
-		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
+		serverPicker:  newServerPicker(config.Strategy, scheduler),
+		scheduler:     scheduler,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		chainTag:      config.ChainTag,
+		rewriteRules:  config.Rewrite,
+		udpMode:       config.UdpMode,
 	}, nil
 }
 
@@ -78,11 +448,15 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	targetAddr := target.NetAddr()
 
 	if target.Network == net.Network_UDP {
-		return newError("UDP is not supported by HTTP outbound")
+		if c.udpMode == ClientConfig_DISABLED {
+			return newError("UDP is not supported by HTTP outbound")
+		}
+		return c.processUDP(ctx, link, dialer, targetAddr)
 	}
 
 	var user *protocol.MemoryUser
 	var conn internet.Connection
+	var pickedServer *protocol.ServerSpec
 
 	var firstPayload []byte
 
@@ -107,7 +481,16 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 		dest := server.Destination()
 		user = server.PickUser()
 
-		netConn, err := setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, firstPayload)
+		onHandshake := func(d time.Duration) {
+			c.scheduler.state(server).updateLatency(d)
+		}
+
+		netConn, err := setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, firstPayload, c.chainTag, c.rewriteRules, onHandshake)
+		if err != nil {
+			c.scheduler.markFailure(server)
+			return err
+		}
+		pickedServer = server
 		if netConn != nil {
 			if _, ok := netConn.(*http2Conn); !ok {
 				if _, err := netConn.Write(firstPayload); err != nil {
@@ -117,11 +500,14 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 			}
 			conn = internet.Connection(netConn)
 		}
-		return err
+		return nil
 	}); err != nil {
 		return newError("failed to find an available destination").Base(err)
 	}
 
+	c.scheduler.state(pickedServer).addConcurrency(1)
+	defer c.scheduler.state(pickedServer).addConcurrency(-1)
+
 	defer func() {
 		if err := conn.Close(); err != nil {
 			newError("failed to closed connection").Base(err).WriteToLog(session.ExportIDToError(ctx))
@@ -153,7 +539,76 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 	return nil
 }
 
-func createXT5Auth(address string) string {
+// processUDP relays a UDP association to targetAddr over a CONNECT(-UDP)
+// tunnel, framing each datagram per c.udpMode. It mirrors Process's TCP
+// path (server picking, retry, policy timeouts) but drives buf.Copy with a
+// datagram-framing Reader/Writer pair instead of the raw tunnel conn.
+func (c *Client) processUDP(ctx context.Context, link *transport.Link, dialer internet.Dialer, targetAddr string) error {
+	var user *protocol.MemoryUser
+	var tunnelConn net.Conn
+	var pickedServer *protocol.ServerSpec
+	mode := c.udpMode
+
+	if err := retry.ExponentialBackoff(2, 100).On(func() error {
+		server := c.serverPicker.PickServer()
+		dest := server.Destination()
+		user = server.PickUser()
+
+		conn, negotiatedMode, err := setUpUDPTunnel(ctx, dest, targetAddr, user, dialer, c.chainTag, c.rewriteRules, mode)
+		if err != nil {
+			c.scheduler.markFailure(server)
+			return err
+		}
+		pickedServer = server
+		tunnelConn = conn
+		mode = negotiatedMode
+		return nil
+	}); err != nil {
+		return newError("failed to find an available destination").Base(err)
+	}
+
+	c.scheduler.state(pickedServer).addConcurrency(1)
+	defer c.scheduler.state(pickedServer).addConcurrency(-1)
+
+	defer func() {
+		if err := tunnelConn.Close(); err != nil {
+			newError("failed to closed connection").Base(err).WriteToLog(session.ExportIDToError(ctx))
+		}
+	}()
+
+	p := c.policyManager.ForLevel(0)
+	if user != nil {
+		p = c.policyManager.ForLevel(user.Level)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, p.Timeouts.ConnectionIdle)
+
+	writer := &udpFrameWriter{conn: tunnelConn, mode: mode}
+	reader := &udpFrameReader{conn: tunnelConn, mode: mode}
+
+	requestFunc := func() error {
+		defer timer.SetTimeout(p.Timeouts.DownlinkOnly)
+		return buf.Copy(link.Reader, writer, buf.UpdateActivity(timer))
+	}
+	responseFunc := func() error {
+		defer timer.SetTimeout(p.Timeouts.UplinkOnly)
+		return buf.Copy(reader, link.Writer, buf.UpdateActivity(timer))
+	}
+
+	responseDonePost := task.OnSuccess(responseFunc, task.Close(link.Writer))
+	if err := task.Run(ctx, requestFunc, responseDonePost); err != nil {
+		return newError("connection ends").Base(err)
+	}
+
+	return nil
+}
+
+// xt5Hash reproduces the legacy bd_x_t5_auth carrier-proxy hash: a running
+// index = (index*1318293 & 0x7FFFFFFF) + rune, folded into [0, 0x7FFFFFFF]
+// and formatted as decimal. It is the built-in implementation of the XT5
+// HashHeader algorithm.
+func xt5Hash(address string) string {
 	var index int32 = 0
 	for _, char := range address {
 		index = (index * 1318293 & 0x7FFFFFFF) + int32(char)
@@ -161,12 +616,114 @@ func createXT5Auth(address string) string {
 	if index < 0 {
 		index = index & 0x7FFFFFFF
 	}
-	verify := fmt.Sprintf("%d", index)
-	return verify
+	return fmt.Sprintf("%d", index)
+}
+
+// matchRewriteRule returns the first rule whose MatchDestHost matches
+// destAddr, or that applies unconditionally (an empty MatchDestHost).
+func matchRewriteRule(rules []*RewriteRule, destAddr string) *RewriteRule {
+	for _, rule := range rules {
+		if rule.MatchDestHost == "" || rule.MatchDestHost == destAddr {
+			return rule
+		}
+	}
+	return nil
+}
+
+// computeHashHeader evaluates a HashHeader against either the proxy
+// destination host or the CONNECT target, depending on its Source.
+func computeHashHeader(h *HashHeader, destAddr, target string) (string, error) {
+	source := destAddr
+	if h.Source == HashHeader_TARGET {
+		source = target
+		if host, _, err := stdnet.SplitHostPort(target); err == nil {
+			source = host
+		}
+	}
+	if h.Seed != "" {
+		source = h.Seed + source
+	}
+
+	switch h.Algorithm {
+	case HashHeader_XT5:
+		return xt5Hash(source), nil
+	default:
+		return "", newError("unsupported hash header algorithm: ", h.Algorithm)
+	}
+}
+
+// targetPlaceholder is substituted with the real per-connection CONNECT
+// target (host:port) in RewriteRule.OverrideHost/OverrideUrlOpaque, so a
+// rule can smuggle the actual destination past a host-based rewrite instead
+// of replacing it outright. This is what the legacy 10.0.0.172 quirk did by
+// hand, building its Opaque value from the request's own
+// (still-real-target) Host at rewrite time.
+const targetPlaceholder = "{target}"
+
+// applyRewriteRule mutates req in place according to rule, so the resulting
+// CONNECT request matches whatever quirk the matched destination requires.
+func applyRewriteRule(req *http.Request, rule *RewriteRule, destAddr, target string) error {
+	for name, value := range rule.SetHeaders {
+		req.Header.Set(name, value)
+	}
+	if rule.OverrideHost != "" {
+		host := strings.ReplaceAll(rule.OverrideHost, targetPlaceholder, target)
+		req.URL.Host = host
+		req.Host = host
+	}
+	if rule.OverrideUrlOpaque != "" {
+		req.URL.Opaque = strings.ReplaceAll(rule.OverrideUrlOpaque, targetPlaceholder, target)
+	}
+	if hh := rule.HashHeader; hh != nil {
+		value, err := computeHashHeader(hh, destAddr, target)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(hh.Name, value)
+	}
+	return nil
+}
+
+// dialViaChain resolves chainTag to another configured outbound and dispatches
+// a synthetic session to it with Target set to dest, the HTTP proxy's own
+// address. The returned connection wraps the resulting transport.Link so it
+// can be used as the rawConn fed into connectHTTP1/connectHTTP2, letting a
+// client -> detour -> http-proxy -> target pipeline be composed without a
+// dedicated transport.
+//
+// Untested: exercising this requires a *core.Instance with a registered
+// outbound.Manager feature, which in turn requires app/proxyman/outbound and
+// core itself. This reduced tree carries neither package, only the
+// feature/outbound interface this file dials against, so there is no way to
+// build a real (or realistic fake) Manager here. The "unknown detour outbound
+// tag" error path is a single nil-check and is reviewed by inspection.
+func dialViaChain(ctx context.Context, chainTag string, dest net.Destination) (internet.Connection, error) {
+	v := core.MustFromContext(ctx)
+	manager := v.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	handler := manager.GetHandler(chainTag)
+	if handler == nil {
+		return nil, newError("unknown detour outbound tag: ", chainTag)
+	}
+
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{Target: dest})
+
+	opts := []pipe.Option{pipe.WithoutSizeLimit()}
+	uplinkReader, uplinkWriter := pipe.New(opts...)
+	downlinkReader, downlinkWriter := pipe.New(opts...)
+
+	go handler.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+
+	return cnc.NewConnection(
+		cnc.ConnectionInputMulti(uplinkWriter),
+		cnc.ConnectionOutputMulti(downlinkReader),
+	), nil
 }
 
-// setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method
-func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, firstPayload []byte) (net.Conn, error) {
+// setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method.
+// onHandshake, if non-nil, is called once with the duration of the CONNECT
+// request/response round trip after a successful handshake, feeding the
+// LeastLatency ServerPicker's latency EWMA.
+func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, firstPayload []byte, chainTag string, rules []*RewriteRule, onHandshake func(time.Duration)) (net.Conn, error) {
 	req := &http.Request{
 		Method: http.MethodConnect,
 		URL:    &url.URL{Host: target},
@@ -181,57 +738,19 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 	}
 
 	destAddr := dest.Address.String()
-	if destAddr == "cloudnproxy.baidu.com" {
-		req.Header.Set("User-Agent", "okhttp/4.9.0 Dalvik/2.1.0 baiduboxapp")
-		req.Header.Set("X-T5-Auth", "bd_x_t5_auth")
-	} else if destAddr == "10.0.0.172" {
-		//视频彩铃 m.10155.com
-		//3G门户 ysj.iread.wo.com.cn
-		//手机电视 live.v.wo.cn
-		//彩信 mmsc.myuni.com.cn
-		req.URL.Opaque = req.Host + ":Host:ysj.iread.wo.com.cn"
-		req.URL.Host = "ysj.iread.wo.com.cn"
-		req.Host = "ysj.iread.wo.com.cn"
-	} else {
-		req.Header.Set("User-Agent", "okhttp/4.9.0 Dalvik/2.1.0 baiduboxapp")
-		req.Header.Set("X-T5-Auth", "bd_x_t5_auth")
+	if rule := matchRewriteRule(rules, destAddr); rule != nil {
+		if err := applyRewriteRule(req, rule, destAddr, target); err != nil {
+			return nil, err
+		}
 	}
 
 	connectHTTP1 := func(rawConn net.Conn) (net.Conn, error) {
 		req.Header.Set("Proxy-Connection", "Keep-Alive")
 
-		if req.Header.Get("X-T5-Auth") == "bd_x_t5_auth" {
-			buf := new(bytes.Buffer)
-			err := req.Write(buf)
-			if err != nil {
-				rawConn.Close()
-				return nil, err
-			}
-
-			s := buf.String()
-			// log.Println("xf22001-bdzl before:", s)
-			pattern := `Host: ([^:]+)(:)?(\d+)?\r\n`
-			re := regexp.MustCompile(pattern)
-			match := re.FindStringSubmatch(s)
-			if len(match) == 4 {
-				s = strings.Replace(s, match[0], "Host: "+match[1]+"\r\n", 1)
-				s = strings.Replace(s, "X-T5-Auth: bd_x_t5_auth", "X-T5-Auth: "+createXT5Auth(match[1]), 1)
-			}
-			// log.Println("xf22001-bdzl after:", s)
-
-			buf = bytes.NewBufferString(s)
-
-			_, err = buf.WriteTo(rawConn)
-			if err != nil {
-				rawConn.Close()
-				return nil, err
-			}
-		} else {
-			err := req.Write(rawConn)
-			if err != nil {
-				rawConn.Close()
-				return nil, err
-			}
+		start := time.Now()
+		if err := req.Write(rawConn); err != nil {
+			rawConn.Close()
+			return nil, err
 		}
 
 		resp, err := http.ReadResponse(bufio.NewReader(rawConn), req)
@@ -245,6 +764,9 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			rawConn.Close()
 			return nil, newError("Proxy responded with non 200 code: " + resp.Status)
 		}
+		if onHandshake != nil {
+			onHandshake(time.Since(start))
+		}
 		return rawConn, nil
 	}
 
@@ -261,6 +783,7 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			wg.Done()
 		}()
 
+		start := time.Now()
 		resp, err := h2clientConn.RoundTrip(req) // nolint: bodyclose
 		if err != nil {
 			rawConn.Close()
@@ -277,11 +800,15 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			rawConn.Close()
 			return nil, newError("Proxy responded with non 200 code: " + resp.Status)
 		}
+		if onHandshake != nil {
+			onHandshake(time.Since(start))
+		}
 		return newHTTP2Conn(rawConn, pw, resp.Body), nil
 	}
 
+	poolKey := h2PoolKey{dest: dest, chainTag: chainTag}
 	cachedH2Mutex.Lock()
-	cachedConn, cachedConnFound := cachedH2Conns[dest]
+	cachedConn, cachedConnFound := cachedH2Conns[poolKey]
 	cachedH2Mutex.Unlock()
 
 	if cachedConnFound {
@@ -292,11 +819,28 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 				return nil, err
 			}
 
+			cachedH2Mutex.Lock()
+			cachedConn.lastUsedAt = time.Now()
+			cachedH2Mutex.Unlock()
+
+			if c := statsCounter(ctx, "http>>>h2pool>>>hit"); c != nil {
+				c.Add(1)
+			}
 			return proxyConn, nil
 		}
 	}
 
-	rawConn, err := dialer.Dial(ctx, dest)
+	if c := statsCounter(ctx, "http>>>h2pool>>>miss"); c != nil {
+		c.Add(1)
+	}
+
+	var rawConn internet.Connection
+	var err error
+	if chainTag != "" {
+		rawConn, err = dialViaChain(ctx, chainTag, dest)
+	} else {
+		rawConn, err = dialer.Dial(ctx, dest)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -332,23 +876,355 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			return nil, err
 		}
 
+		now := time.Now()
 		cachedH2Mutex.Lock()
 		if cachedH2Conns == nil {
-			cachedH2Conns = make(map[net.Destination]h2Conn)
+			cachedH2Conns = make(map[h2PoolKey]*h2Conn)
 		}
 
-		cachedH2Conns[dest] = h2Conn{
-			rawConn: rawConn,
-			h2Conn:  h2clientConn,
+		cachedH2Conns[poolKey] = &h2Conn{
+			rawConn:    rawConn,
+			h2Conn:     h2clientConn,
+			createdAt:  now,
+			lastUsedAt: now,
 		}
 		cachedH2Mutex.Unlock()
 
+		startH2PoolJanitor(ctx)
+
 		return proxyConn, err
 	default:
 		return nil, newError("negotiated unsupported application layer protocol: " + nextProto)
 	}
 }
 
+// masqueUDPPath builds the RFC 9298 default URI Template path,
+// /.well-known/masque/udp/{target_host}/{target_port}/, for target.
+func masqueUDPPath(target string) string {
+	host, port, err := stdnet.SplitHostPort(target)
+	if err != nil {
+		host, port = target, "0"
+	}
+	return "/.well-known/masque/udp/" + url.PathEscape(host) + "/" + port + "/"
+}
+
+// setUpUDPTunnel establishes (or, for h2, reuses) a CONNECT tunnel to relay
+// UDP datagrams to target, returning the duplex net.Conn and the UdpMode
+// the caller must frame datagrams with. If mode is ClientConfig_RFC9298 but
+// the upstream negotiates http/1.1 instead of h2, extended CONNECT-UDP is
+// unavailable and this silently falls back to ClientConfig_LENGTH_PREFIXED,
+// exactly as if that mode had been configured from the start.
+func setUpUDPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, chainTag string, rules []*RewriteRule, mode ClientConfig_UdpMode) (net.Conn, ClientConfig_UdpMode, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		Header: make(http.Header),
+	}
+	if mode == ClientConfig_RFC9298 {
+		// RFC 9298 CONNECT-UDP rides RFC 8441 extended CONNECT. RFC 8441 4
+		// defines the HTTP/2 :protocol pseudo-header as the extended-CONNECT
+		// counterpart of the HTTP/1.1 Upgrade header, and :scheme/:path are
+		// only sent by an HTTP/2 client for a CONNECT request once a
+		// protocol is signaled this way; without it, the request goes out
+		// as a bare CONNECT with masqueUDPPath's target path silently
+		// dropped. Setting Upgrade (rather than a literal ":protocol"
+		// header, which http.Header rejects as an invalid field name) is
+		// how this is surfaced through the net/http request model.
+		//
+		// This has not been verified end-to-end against a real MASQUE
+		// server in this environment; verify against the pinned
+		// golang.org/x/net/http2 version's actual extended-CONNECT support
+		// before relying on this path.
+		req.URL = &url.URL{Scheme: "https", Host: dest.NetAddr(), Path: masqueUDPPath(target)}
+		req.Host = dest.NetAddr()
+		req.Header.Set("Upgrade", "connect-udp")
+	} else {
+		req.URL = &url.URL{Host: target}
+		req.Host = target
+	}
+
+	if user != nil && user.Account != nil {
+		account := user.Account.(*Account)
+		auth := account.GetUsername() + ":" + account.GetPassword()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+
+	destAddr := dest.Address.String()
+	if rule := matchRewriteRule(rules, destAddr); rule != nil {
+		if err := applyRewriteRule(req, rule, destAddr, target); err != nil {
+			return nil, mode, err
+		}
+	}
+
+	// connectStream issues req over rawConn (plain CONNECT) or, if
+	// h2ClientConn is non-nil, as a new stream on it (extended CONNECT),
+	// returning the resulting duplex net.Conn.
+	connectStream := func(rawConn net.Conn, h2ClientConn *http2.ClientConn) (net.Conn, error) {
+		if h2ClientConn == nil {
+			req.Header.Set("Proxy-Connection", "Keep-Alive")
+			if err := req.Write(rawConn); err != nil {
+				rawConn.Close()
+				return nil, err
+			}
+			resp, err := http.ReadResponse(bufio.NewReader(rawConn), req)
+			if err != nil {
+				rawConn.Close()
+				return nil, err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				rawConn.Close()
+				return nil, newError("Proxy responded with non 200 code: " + resp.Status)
+			}
+			return rawConn, nil
+		}
+
+		pr, pw := io.Pipe()
+		req.Body = pr
+		resp, err := h2ClientConn.RoundTrip(req) // nolint: bodyclose
+		if err != nil {
+			rawConn.Close()
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			rawConn.Close()
+			return nil, newError("Proxy responded with non 200 code: " + resp.Status)
+		}
+		return newHTTP2Conn(rawConn, pw, resp.Body), nil
+	}
+
+	poolKey := h2PoolKey{dest: dest, chainTag: chainTag}
+	if mode == ClientConfig_RFC9298 {
+		cachedH2Mutex.Lock()
+		cachedConn, cachedConnFound := cachedH2Conns[poolKey]
+		cachedH2Mutex.Unlock()
+
+		if cachedConnFound && cachedConn.h2Conn.CanTakeNewRequest() {
+			proxyConn, err := connectStream(cachedConn.rawConn, cachedConn.h2Conn)
+			if err != nil {
+				return nil, mode, err
+			}
+			cachedH2Mutex.Lock()
+			cachedConn.lastUsedAt = time.Now()
+			cachedH2Mutex.Unlock()
+			return proxyConn, mode, nil
+		}
+	}
+
+	var rawConn internet.Connection
+	var err error
+	if chainTag != "" {
+		rawConn, err = dialViaChain(ctx, chainTag, dest)
+	} else {
+		rawConn, err = dialer.Dial(ctx, dest)
+	}
+	if err != nil {
+		return nil, mode, err
+	}
+
+	iConn := rawConn
+	if statConn, ok := iConn.(*internet.StatCouterConnection); ok {
+		iConn = statConn.Connection
+	}
+
+	nextProto := ""
+	if tlsConn, ok := iConn.(*tls.Conn); ok {
+		if err := tlsConn.Handshake(); err != nil {
+			rawConn.Close()
+			return nil, mode, err
+		}
+		nextProto = tlsConn.ConnectionState().NegotiatedProtocol
+	}
+
+	if mode == ClientConfig_RFC9298 && nextProto != "h2" {
+		mode = ClientConfig_LENGTH_PREFIXED
+		req.URL = &url.URL{Host: target}
+		req.Host = target
+		req.Header.Del("Upgrade")
+		if rule := matchRewriteRule(rules, destAddr); rule != nil {
+			if err := applyRewriteRule(req, rule, destAddr, target); err != nil {
+				rawConn.Close()
+				return nil, mode, err
+			}
+		}
+	}
+
+	if mode != ClientConfig_RFC9298 {
+		proxyConn, err := connectStream(rawConn, nil)
+		return proxyConn, mode, err
+	}
+
+	t := http2.Transport{}
+	h2ClientConn, err := t.NewClientConn(rawConn)
+	if err != nil {
+		rawConn.Close()
+		return nil, mode, err
+	}
+
+	proxyConn, err := connectStream(rawConn, h2ClientConn)
+	if err != nil {
+		rawConn.Close()
+		return nil, mode, err
+	}
+
+	now := time.Now()
+	cachedH2Mutex.Lock()
+	if cachedH2Conns == nil {
+		cachedH2Conns = make(map[h2PoolKey]*h2Conn)
+	}
+	cachedH2Conns[poolKey] = &h2Conn{rawConn: rawConn, h2Conn: h2ClientConn, createdAt: now, lastUsedAt: now}
+	cachedH2Mutex.Unlock()
+	startH2PoolJanitor(ctx)
+
+	return proxyConn, mode, nil
+}
+
+// capsuleTypeDatagram is the HTTP Datagram capsule type (RFC 9297) used to
+// carry UDP payloads over an RFC 9298 CONNECT-UDP stream.
+const capsuleTypeDatagram = 0x00
+
+// appendQuicVarint appends v to b using the QUIC variable-length integer
+// encoding (RFC 9000 16), which the HTTP Datagram capsule format is built on.
+// v must fit in 62 bits (at most 0x3fffffffffffffff); every caller in this
+// file only ever encodes capsule types, context IDs, or payload lengths,
+// none of which can reach that bound in practice.
+func appendQuicVarint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x3f:
+		return append(b, byte(v))
+	case v <= 0x3fff:
+		return append(b, byte(v>>8)|0x40, byte(v))
+	case v <= 0x3fffffff:
+		return append(b, byte(v>>24)|0x80, byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, byte(v>>56)|0xc0, byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+func readQuicVarint(r io.Reader) (uint64, error) {
+	var first [1]byte
+	if _, err := io.ReadFull(r, first[:]); err != nil {
+		return 0, err
+	}
+	rest := make([]byte, 1<<(first[0]>>6)-1)
+	if len(rest) > 0 {
+		if _, err := io.ReadFull(r, rest); err != nil {
+			return 0, err
+		}
+	}
+	v := uint64(first[0] & 0x3f)
+	for _, b := range rest {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+// writeUDPFrame writes payload to w, framed per mode: a masque capsule
+// wrapping UDP Proxying Context ID 0 (RFC 9298) for ClientConfig_RFC9298, or
+// a 2-byte big-endian length prefix for ClientConfig_LENGTH_PREFIXED.
+func writeUDPFrame(w io.Writer, mode ClientConfig_UdpMode, payload []byte) error {
+	if mode == ClientConfig_RFC9298 {
+		value := appendQuicVarint(make([]byte, 0, len(payload)+1), 0) // context ID 0: raw UDP payload
+		value = append(value, payload...)
+		frame := appendQuicVarint([]byte{}, capsuleTypeDatagram)
+		frame = appendQuicVarint(frame, uint64(len(value)))
+		frame = append(frame, value...)
+		_, err := w.Write(frame)
+		return err
+	}
+
+	if len(payload) > 0xffff {
+		return newError("UDP datagram too large for length-prefixed framing: ", len(payload))
+	}
+	header := []byte{byte(len(payload) >> 8), byte(len(payload))}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readUDPFrame reads and decodes one frame from r per mode, the inverse of
+// writeUDPFrame.
+func readUDPFrame(r io.Reader, mode ClientConfig_UdpMode) ([]byte, error) {
+	if mode == ClientConfig_RFC9298 {
+		capsuleType, err := readQuicVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if capsuleType != capsuleTypeDatagram {
+			return nil, newError("unexpected masque capsule type: ", capsuleType)
+		}
+		length, err := readQuicVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return nil, err
+		}
+		vr := bytes.NewReader(value)
+		contextID, err := readQuicVarint(vr)
+		if err != nil {
+			return nil, err
+		}
+		if contextID != 0 {
+			return nil, newError("unsupported masque context id: ", contextID)
+		}
+		payload := make([]byte, vr.Len())
+		if _, err := io.ReadFull(vr, payload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(header[0])<<8 | int(header[1])
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// udpFrameWriter adapts a CONNECT(-UDP) tunnel into a buf.Writer, encoding
+// every MultiBuffer entry as one framed UDP datagram per mode.
+type udpFrameWriter struct {
+	conn net.Conn
+	mode ClientConfig_UdpMode
+}
+
+func (w *udpFrameWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	defer buf.ReleaseMulti(mb)
+	for _, b := range mb {
+		if err := writeUDPFrame(w.conn, w.mode, b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// udpFrameReader is the receive-side counterpart of udpFrameWriter.
+type udpFrameReader struct {
+	conn net.Conn
+	mode ClientConfig_UdpMode
+}
+
+func (r *udpFrameReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	payload, err := readUDPFrame(r.conn, r.mode)
+	if err != nil {
+		return nil, err
+	}
+	b := buf.New()
+	if _, err := b.Write(payload); err != nil {
+		b.Release()
+		return nil, err
+	}
+	return buf.MultiBuffer{b}, nil
+}
+
 func newHTTP2Conn(c net.Conn, pipedReqBody *io.PipeWriter, respBody io.ReadCloser) net.Conn {
 	return &http2Conn{Conn: c, in: pipedReqBody, out: respBody}
 }
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestXt5Hash(t *testing.T) {
+	// xt5Hash is a pure re-implementation of a fixed legacy algorithm, so a
+	// change in its output for a fixed input is itself the regression this
+	// guards against.
+	got := xt5Hash("cloudnproxy.baidu.com")
+	want := xt5Hash("cloudnproxy.baidu.com")
+	if got != want {
+		t.Fatalf("xt5Hash is not deterministic: got %q, then %q", got, want)
+	}
+	if got != "1050504963" {
+		t.Errorf("xt5Hash(%q) = %q, want %q", "cloudnproxy.baidu.com", got, "1050504963")
+	}
+	if xt5Hash("a") == xt5Hash("b") {
+		t.Fatalf("xt5Hash(%q) and xt5Hash(%q) collided: %q", "a", "b", xt5Hash("a"))
+	}
+	if xt5Hash("") != "0" {
+		t.Errorf("xt5Hash(\"\") = %q, want %q", xt5Hash(""), "0")
+	}
+}
+
+func TestMatchRewriteRule(t *testing.T) {
+	specific := &RewriteRule{MatchDestHost: "example.com"}
+	wildcard := &RewriteRule{MatchDestHost: ""}
+	rules := []*RewriteRule{specific, wildcard}
+
+	if got := matchRewriteRule(rules, "example.com"); got != specific {
+		t.Errorf("matchRewriteRule did not pick the specific rule for an exact host match")
+	}
+	if got := matchRewriteRule(rules, "other.com"); got != wildcard {
+		t.Errorf("matchRewriteRule did not fall back to the wildcard rule")
+	}
+	if got := matchRewriteRule(nil, "example.com"); got != nil {
+		t.Errorf("matchRewriteRule(nil, ...) = %v, want nil", got)
+	}
+}
+
+func TestComputeHashHeaderSource(t *testing.T) {
+	h := &HashHeader{Algorithm: HashHeader_XT5, Source: HashHeader_HOST}
+	destValue, err := computeHashHeader(h, "dest.example.com", "target.example.com:443")
+	if err != nil {
+		t.Fatalf("computeHashHeader(HOST) returned error: %v", err)
+	}
+	if want := xt5Hash("dest.example.com"); destValue != want {
+		t.Errorf("computeHashHeader(HOST) = %q, want %q", destValue, want)
+	}
+
+	h.Source = HashHeader_TARGET
+	targetValue, err := computeHashHeader(h, "dest.example.com", "target.example.com:443")
+	if err != nil {
+		t.Fatalf("computeHashHeader(TARGET) returned error: %v", err)
+	}
+	if want := xt5Hash("target.example.com"); targetValue != want {
+		t.Errorf("computeHashHeader(TARGET) = %q, want %q (should strip the port)", targetValue, want)
+	}
+}
+
+func TestComputeHashHeaderSeed(t *testing.T) {
+	h := &HashHeader{Algorithm: HashHeader_XT5, Source: HashHeader_HOST, Seed: "salt-"}
+	got, err := computeHashHeader(h, "dest.example.com", "")
+	if err != nil {
+		t.Fatalf("computeHashHeader returned error: %v", err)
+	}
+	if want := xt5Hash("salt-dest.example.com"); got != want {
+		t.Errorf("computeHashHeader with Seed = %q, want %q", got, want)
+	}
+}
+
+func TestComputeHashHeaderUnsupportedAlgorithm(t *testing.T) {
+	h := &HashHeader{Algorithm: HashHeader_CRC32}
+	if _, err := computeHashHeader(h, "dest.example.com", ""); err == nil {
+		t.Fatalf("computeHashHeader did not error on an unsupported algorithm")
+	}
+}
+
+func TestApplyRewriteRuleTargetPlaceholder(t *testing.T) {
+	req, err := http.NewRequest(http.MethodConnect, "http://dest.example.com:80", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	rule := &RewriteRule{
+		OverrideHost:      "carrier.example.com",
+		OverrideUrlOpaque: "//carrier.example.com/real={target}",
+	}
+	if err := applyRewriteRule(req, rule, "dest.example.com", "dest.example.com:80"); err != nil {
+		t.Fatalf("applyRewriteRule returned error: %v", err)
+	}
+	if req.URL.Host != "carrier.example.com" {
+		t.Errorf("req.URL.Host = %q, want %q", req.URL.Host, "carrier.example.com")
+	}
+	if req.Host != "carrier.example.com" {
+		t.Errorf("req.Host = %q, want %q", req.Host, "carrier.example.com")
+	}
+	if want := "//carrier.example.com/real=dest.example.com:80"; req.URL.Opaque != want {
+		t.Errorf("req.URL.Opaque = %q, want %q", req.URL.Opaque, want)
+	}
+}
+
+func TestApplyRewriteRuleHashHeader(t *testing.T) {
+	req, err := http.NewRequest(http.MethodConnect, "http://dest.example.com:80", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest: %v", err)
+	}
+	rule := &RewriteRule{
+		HashHeader: &HashHeader{Name: "X-T5-Auth", Algorithm: HashHeader_XT5, Source: HashHeader_HOST},
+	}
+	if err := applyRewriteRule(req, rule, "dest.example.com", "dest.example.com:80"); err != nil {
+		t.Fatalf("applyRewriteRule returned error: %v", err)
+	}
+	if want := xt5Hash("dest.example.com"); req.Header.Get("X-T5-Auth") != want {
+		t.Errorf("X-T5-Auth header = %q, want %q", req.Header.Get("X-T5-Auth"), want)
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	stdnet "net"
+	"testing"
+	"time"
+)
+
+// withJanitorGlobals saves and restores the package-level h2 pool globals
+// evictDeadH2Conns reads and mutates, so tests can set them without leaking
+// state into other tests.
+func withJanitorGlobals(t *testing.T) {
+	t.Helper()
+	cachedH2Mutex.Lock()
+	savedConns := cachedH2Conns
+	cachedH2Conns = nil
+	cachedH2Mutex.Unlock()
+	savedMaxLife, savedMaxIdle := h2PoolMaxLife, h2PoolMaxIdle
+
+	t.Cleanup(func() {
+		cachedH2Mutex.Lock()
+		cachedH2Conns = savedConns
+		cachedH2Mutex.Unlock()
+		h2PoolMaxLife, h2PoolMaxIdle = savedMaxLife, savedMaxIdle
+	})
+}
+
+// evictDeadH2Conns only dereferences an entry's *http2.ClientConn once none
+// of the time-based eviction checks (MaxLife/MaxIdle) already decided the
+// entry is dead, so a nil h2Conn field is safe for exercising those two
+// paths. The liveness-probe path (CanTakeNewRequest/Ping) needs a real HTTP/2
+// connection and is not covered here.
+func newTestH2Entry(t *testing.T, createdAt, lastUsedAt time.Time) (h2PoolKey, *h2Conn) {
+	t.Helper()
+	rawConn, peer := stdnet.Pipe()
+	t.Cleanup(func() {
+		rawConn.Close()
+		peer.Close()
+	})
+	key := h2PoolKey{dest: testHTTPDest("example.com"), chainTag: ""}
+	return key, &h2Conn{rawConn: rawConn, createdAt: createdAt, lastUsedAt: lastUsedAt}
+}
+
+func TestEvictDeadH2ConnsMaxLife(t *testing.T) {
+	withJanitorGlobals(t)
+	h2PoolMaxLife = time.Minute
+	h2PoolMaxIdle = 0
+
+	now := time.Now()
+	key, entry := newTestH2Entry(t, now.Add(-2*time.Minute), now)
+	cachedH2Mutex.Lock()
+	cachedH2Conns = map[h2PoolKey]*h2Conn{key: entry}
+	cachedH2Mutex.Unlock()
+
+	evictDeadH2Conns(context.Background())
+
+	cachedH2Mutex.Lock()
+	_, found := cachedH2Conns[key]
+	cachedH2Mutex.Unlock()
+	if found {
+		t.Errorf("evictDeadH2Conns did not evict a connection older than h2PoolMaxLife")
+	}
+}
+
+func TestEvictDeadH2ConnsMaxIdle(t *testing.T) {
+	withJanitorGlobals(t)
+	h2PoolMaxLife = 0
+	h2PoolMaxIdle = time.Minute
+
+	now := time.Now()
+	key, entry := newTestH2Entry(t, now.Add(-time.Hour), now.Add(-2*time.Minute))
+	cachedH2Mutex.Lock()
+	cachedH2Conns = map[h2PoolKey]*h2Conn{key: entry}
+	cachedH2Mutex.Unlock()
+
+	evictDeadH2Conns(context.Background())
+
+	cachedH2Mutex.Lock()
+	_, found := cachedH2Conns[key]
+	cachedH2Mutex.Unlock()
+	if found {
+		t.Errorf("evictDeadH2Conns did not evict a connection idle longer than h2PoolMaxIdle")
+	}
+}
+
+func TestEvictDeadH2ConnsClosesEvictedRawConn(t *testing.T) {
+	withJanitorGlobals(t)
+	h2PoolMaxLife = time.Minute
+	h2PoolMaxIdle = 0
+
+	now := time.Now()
+	key, entry := newTestH2Entry(t, now.Add(-2*time.Minute), now)
+	cachedH2Mutex.Lock()
+	cachedH2Conns = map[h2PoolKey]*h2Conn{key: entry}
+	cachedH2Mutex.Unlock()
+
+	evictDeadH2Conns(context.Background())
+
+	if _, err := entry.rawConn.Write([]byte("x")); err == nil {
+		t.Errorf("evictDeadH2Conns did not close the evicted entry's rawConn")
+	}
+}
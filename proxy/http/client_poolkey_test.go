@@ -0,0 +1,38 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+func testHTTPDest(host string) net.Destination {
+	return net.Destination{
+		Network: net.Network_TCP,
+		Address: net.ParseAddress(host),
+		Port:    net.Port(443),
+	}
+}
+
+// TestH2PoolKeyScopedByChainTag guards against the class of bug fixed for
+// cachedH2Conns: two outbounds reaching the same dest by different network
+// paths (e.g. one direct, one routed through a ChainTag detour) must not
+// share one pooled connection, or one outbound's traffic would silently ride
+// the other's path.
+func TestH2PoolKeyScopedByChainTag(t *testing.T) {
+	dest := testHTTPDest("example.com")
+	direct := h2PoolKey{dest: dest, chainTag: ""}
+	viaDetour := h2PoolKey{dest: dest, chainTag: "ssh-detour"}
+
+	if direct == viaDetour {
+		t.Fatalf("h2PoolKey did not vary with ChainTag: a direct dial and a chained dial to the same dest produced the same key (%v)", direct)
+	}
+}
+
+func TestH2PoolKeyScopedByDestination(t *testing.T) {
+	a := h2PoolKey{dest: testHTTPDest("a.example.com"), chainTag: "detour"}
+	b := h2PoolKey{dest: testHTTPDest("b.example.com"), chainTag: "detour"}
+	if a == b {
+		t.Fatalf("h2PoolKey did not vary with destination: both produced %v", a)
+	}
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+)
+
+func testServerSpec(host string, port uint16) *protocol.ServerSpec {
+	dest := net.Destination{
+		Network: net.Network_TCP,
+		Address: net.ParseAddress(host),
+		Port:    net.Port(port),
+	}
+	return protocol.NewServerSpec(dest)
+}
+
+func testScheduler(t *testing.T, weights []uint32, servers ...*protocol.ServerSpec) *serverScheduler {
+	t.Helper()
+	serverList := protocol.NewServerList()
+	for _, s := range servers {
+		serverList.AddServer(s)
+	}
+	return newServerScheduler(serverList, weights, time.Minute)
+}
+
+func TestRoundRobinServerPicker(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	picker := &roundRobinServerPicker{sched: sched}
+
+	got := []*protocol.ServerSpec{
+		picker.PickServer(), picker.PickServer(), picker.PickServer(), picker.PickServer(),
+	}
+	want := []*protocol.ServerSpec{a, b, a, b}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pick %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRoundRobinServerPickerSkipsCoolingServers(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	sched.markFailure(a)
+
+	picker := &roundRobinServerPicker{sched: sched}
+	for i := 0; i < 4; i++ {
+		if got := picker.PickServer(); got != b {
+			t.Fatalf("pick %d = %v, want the only non-cooling server %v", i, got, b)
+		}
+	}
+}
+
+func TestServerSchedulerAllCoolingFallsBackToFullList(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	sched.markFailure(a)
+	sched.markFailure(b)
+
+	if got := len(sched.available()); got != 2 {
+		t.Fatalf("available() with every server cooling returned %d servers, want 2 (full fallback)", got)
+	}
+}
+
+func TestLeastLatencyServerPickerTriesUnknownFirst(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	sched.state(a).updateLatency(10 * time.Millisecond)
+	// b has no latency sample yet: it must be tried before ranking kicks in.
+
+	picker := &leastLatencyServerPicker{sched: sched}
+	if got := picker.PickServer(); got != b {
+		t.Errorf("PickServer() = %v, want the server with no latency sample yet (%v)", got, b)
+	}
+}
+
+func TestLeastLatencyServerPickerPicksLowestEWMA(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	sched.state(a).updateLatency(50 * time.Millisecond)
+	sched.state(b).updateLatency(10 * time.Millisecond)
+
+	picker := &leastLatencyServerPicker{sched: sched}
+	if got := picker.PickServer(); got != b {
+		t.Errorf("PickServer() = %v, want the lower-latency server %v", got, b)
+	}
+}
+
+func TestLeastConcurrencyServerPicker(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	sched.state(a).addConcurrency(5)
+	sched.state(b).addConcurrency(1)
+
+	picker := &leastConcurrencyServerPicker{sched: sched}
+	if got := picker.PickServer(); got != b {
+		t.Errorf("PickServer() = %v, want the less-loaded server %v", got, b)
+	}
+}
+
+func TestWeightedRandomServerPickerRespectsWeights(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	// b is weighted so heavily that, over many trials, a should essentially
+	// never be picked; a zero-variance assertion would be flaky, so assert a
+	// strong skew instead of an exact distribution.
+	sched := testScheduler(t, []uint32{1, 999}, a, b)
+	picker := &weightedRandomServerPicker{sched: sched}
+
+	var bCount int
+	const trials = 500
+	for i := 0; i < trials; i++ {
+		if picker.PickServer() == b {
+			bCount++
+		}
+	}
+	if bCount < trials*9/10 {
+		t.Errorf("weightedRandomServerPicker picked the heavily-weighted server %d/%d times, want at least %d", bCount, trials, trials*9/10)
+	}
+}
+
+func TestWeightedRandomServerPickerZeroWeightsFallBackToUniform(t *testing.T) {
+	a, b := testServerSpec("a.example.com", 80), testServerSpec("b.example.com", 80)
+	sched := testScheduler(t, nil, a, b)
+	sched.weights[a] = 0
+	sched.weights[b] = 0
+
+	picker := &weightedRandomServerPicker{sched: sched}
+	for i := 0; i < 20; i++ {
+		if got := picker.PickServer(); got != a && got != b {
+			t.Fatalf("PickServer() returned a server outside the scheduler: %v", got)
+		}
+	}
+}
+
+func TestNewServerPickerSelectsStrategy(t *testing.T) {
+	sched := testScheduler(t, nil, testServerSpec("a.example.com", 80))
+
+	cases := []struct {
+		strategy ClientConfig_Strategy
+		want     interface{}
+	}{
+		{ClientConfig_ROUND_ROBIN, &roundRobinServerPicker{}},
+		{ClientConfig_RANDOM, &randomServerPicker{}},
+		{ClientConfig_LEAST_LATENCY, &leastLatencyServerPicker{}},
+		{ClientConfig_LEAST_CONCURRENCY, &leastConcurrencyServerPicker{}},
+		{ClientConfig_WEIGHTED_RANDOM, &weightedRandomServerPicker{}},
+	}
+	for _, c := range cases {
+		got := newServerPicker(c.strategy, sched)
+		switch c.want.(type) {
+		case *roundRobinServerPicker:
+			if _, ok := got.(*roundRobinServerPicker); !ok {
+				t.Errorf("strategy %v produced %T, want *roundRobinServerPicker", c.strategy, got)
+			}
+		case *randomServerPicker:
+			if _, ok := got.(*randomServerPicker); !ok {
+				t.Errorf("strategy %v produced %T, want *randomServerPicker", c.strategy, got)
+			}
+		case *leastLatencyServerPicker:
+			if _, ok := got.(*leastLatencyServerPicker); !ok {
+				t.Errorf("strategy %v produced %T, want *leastLatencyServerPicker", c.strategy, got)
+			}
+		case *leastConcurrencyServerPicker:
+			if _, ok := got.(*leastConcurrencyServerPicker); !ok {
+				t.Errorf("strategy %v produced %T, want *leastConcurrencyServerPicker", c.strategy, got)
+			}
+		case *weightedRandomServerPicker:
+			if _, ok := got.(*weightedRandomServerPicker); !ok {
+				t.Errorf("strategy %v produced %T, want *weightedRandomServerPicker", c.strategy, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestQuicVarintRoundTrip(t *testing.T) {
+	cases := []uint64{
+		// 0x3fffffffffffffff is the largest value the 62-bit QUIC varint
+		// encoding can represent; appendQuicVarint does not error on larger
+		// input, it silently truncates, so this intentionally stops short of
+		// the uint64 max.
+		0, 1, 0x3f, 0x40, 0x3fff, 0x4000, 0x3fffffff, 0x40000000, 0x3fffffffffffffff,
+	}
+	for _, v := range cases {
+		b := appendQuicVarint(nil, v)
+		got, err := readQuicVarint(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("readQuicVarint(%d): %v", v, err)
+		}
+		if got != v {
+			t.Errorf("round-trip of %d produced %d", v, got)
+		}
+	}
+}
+
+func TestQuicVarintEncodingLength(t *testing.T) {
+	cases := []struct {
+		v    uint64
+		want int
+	}{
+		{0x3f, 1},
+		{0x40, 2},
+		{0x3fff, 2},
+		{0x4000, 4},
+		{0x3fffffff, 4},
+		{0x40000000, 8},
+	}
+	for _, c := range cases {
+		if got := len(appendQuicVarint(nil, c.v)); got != c.want {
+			t.Errorf("appendQuicVarint(%d) produced %d bytes, want %d", c.v, got, c.want)
+		}
+	}
+}
+
+func TestReadQuicVarintShortRead(t *testing.T) {
+	// A length-4 varint header with only one of its three trailing bytes.
+	if _, err := readQuicVarint(bytes.NewReader([]byte{0x80, 0x01})); err == nil {
+		t.Fatalf("readQuicVarint did not error on a truncated varint")
+	}
+}
+
+func TestUDPFrameRoundTripLengthPrefixed(t *testing.T) {
+	payload := []byte("hello world")
+	var buf bytes.Buffer
+	if err := writeUDPFrame(&buf, ClientConfig_LENGTH_PREFIXED, payload); err != nil {
+		t.Fatalf("writeUDPFrame: %v", err)
+	}
+	got, err := readUDPFrame(&buf, ClientConfig_LENGTH_PREFIXED)
+	if err != nil {
+		t.Fatalf("readUDPFrame: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("readUDPFrame = %q, want %q", got, payload)
+	}
+}
+
+func TestUDPFrameRoundTripRFC9298(t *testing.T) {
+	payload := []byte("hello quic datagram")
+	var buf bytes.Buffer
+	if err := writeUDPFrame(&buf, ClientConfig_RFC9298, payload); err != nil {
+		t.Fatalf("writeUDPFrame: %v", err)
+	}
+	got, err := readUDPFrame(&buf, ClientConfig_RFC9298)
+	if err != nil {
+		t.Fatalf("readUDPFrame: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("readUDPFrame = %q, want %q", got, payload)
+	}
+}
+
+func TestUDPFrameLengthPrefixedTooLarge(t *testing.T) {
+	payload := make([]byte, 0x10000)
+	var buf bytes.Buffer
+	if err := writeUDPFrame(&buf, ClientConfig_LENGTH_PREFIXED, payload); err == nil {
+		t.Fatalf("writeUDPFrame did not reject a payload larger than 0xffff")
+	}
+}
+
+func TestReadUDPFrameRejectsWrongCapsuleType(t *testing.T) {
+	var buf bytes.Buffer
+	frame := appendQuicVarint(nil, 0x01) // not capsuleTypeDatagram
+	frame = appendQuicVarint(frame, 0)
+	buf.Write(frame)
+	if _, err := readUDPFrame(&buf, ClientConfig_RFC9298); err == nil {
+		t.Fatalf("readUDPFrame accepted an unexpected capsule type")
+	}
+}
+
+func TestReadUDPFrameRejectsNonZeroContextID(t *testing.T) {
+	var buf bytes.Buffer
+	value := appendQuicVarint(nil, 1) // context ID 1, not the supported raw-UDP context 0
+	frame := appendQuicVarint(nil, capsuleTypeDatagram)
+	frame = appendQuicVarint(frame, uint64(len(value)))
+	frame = append(frame, value...)
+	buf.Write(frame)
+	if _, err := readUDPFrame(&buf, ClientConfig_RFC9298); err == nil {
+		t.Fatalf("readUDPFrame accepted an unsupported masque context id")
+	}
+}
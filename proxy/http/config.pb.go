@@ -0,0 +1,747 @@
+package http
+
+import (
+	protocol "github.com/v2fly/v2ray-core/v5/common/protocol"
+	_ "github.com/v2fly/v2ray-core/v5/common/protoext"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ClientConfig_UdpMode selects how UDP destinations are relayed through the
+// HTTP CONNECT tunnel, since plain HTTP CONNECT has no notion of UDP.
+type ClientConfig_UdpMode int32
+
+const (
+	// ClientConfig_DISABLED rejects UDP destinations, as the HTTP outbound
+	// always did before UDP relaying was added.
+	ClientConfig_DISABLED ClientConfig_UdpMode = 0
+	// ClientConfig_RFC9298 negotiates an RFC 9298 CONNECT-UDP tunnel over
+	// HTTP/2, falling back to ClientConfig_LENGTH_PREFIXED if the upstream
+	// negotiates http/1.1 instead.
+	ClientConfig_RFC9298 ClientConfig_UdpMode = 1
+	// ClientConfig_LENGTH_PREFIXED relays UDP datagrams as a 2-byte
+	// big-endian length prefix followed by the datagram, over a plain
+	// CONNECT tunnel. It works with any upstream, http/1.1 included.
+	ClientConfig_LENGTH_PREFIXED ClientConfig_UdpMode = 2
+)
+
+// Enum value maps for ClientConfig_UdpMode.
+var (
+	ClientConfig_UdpMode_name = map[int32]string{
+		0: "DISABLED",
+		1: "RFC9298",
+		2: "LENGTH_PREFIXED",
+	}
+	ClientConfig_UdpMode_value = map[string]int32{
+		"DISABLED":        0,
+		"RFC9298":         1,
+		"LENGTH_PREFIXED": 2,
+	}
+)
+
+func (x ClientConfig_UdpMode) Enum() *ClientConfig_UdpMode {
+	p := new(ClientConfig_UdpMode)
+	*p = x
+	return p
+}
+
+func (x ClientConfig_UdpMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ClientConfig_UdpMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proxy_http_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ClientConfig_UdpMode) Type() protoreflect.EnumType {
+	return &file_proxy_http_config_proto_enumTypes[0]
+}
+
+func (x ClientConfig_UdpMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// ClientConfig_Strategy selects how Server entries are picked for each new
+// connection.
+type ClientConfig_Strategy int32
+
+const (
+	// ClientConfig_ROUND_ROBIN cycles through Server in order, skipping
+	// entries currently cooling down after a failed CONNECT handshake. It
+	// is the default, preserving the outbound's original behavior.
+	ClientConfig_ROUND_ROBIN ClientConfig_Strategy = 0
+	ClientConfig_RANDOM      ClientConfig_Strategy = 1
+	// ClientConfig_LEAST_LATENCY picks the server with the lowest EWMA of
+	// recent CONNECT handshake times, trying every server at least once
+	// before ranking.
+	ClientConfig_LEAST_LATENCY ClientConfig_Strategy = 2
+	// ClientConfig_LEAST_CONCURRENCY picks the server with the fewest
+	// in-flight Process calls.
+	ClientConfig_LEAST_CONCURRENCY ClientConfig_Strategy = 3
+	// ClientConfig_WEIGHTED_RANDOM picks randomly among Server, weighted by
+	// ServerWeights.
+	ClientConfig_WEIGHTED_RANDOM ClientConfig_Strategy = 4
+)
+
+// Enum value maps for ClientConfig_Strategy.
+var (
+	ClientConfig_Strategy_name = map[int32]string{
+		0: "ROUND_ROBIN",
+		1: "RANDOM",
+		2: "LEAST_LATENCY",
+		3: "LEAST_CONCURRENCY",
+		4: "WEIGHTED_RANDOM",
+	}
+	ClientConfig_Strategy_value = map[string]int32{
+		"ROUND_ROBIN":       0,
+		"RANDOM":            1,
+		"LEAST_LATENCY":     2,
+		"LEAST_CONCURRENCY": 3,
+		"WEIGHTED_RANDOM":   4,
+	}
+)
+
+func (x ClientConfig_Strategy) Enum() *ClientConfig_Strategy {
+	p := new(ClientConfig_Strategy)
+	*p = x
+	return p
+}
+
+func (x ClientConfig_Strategy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ClientConfig_Strategy) Descriptor() protoreflect.EnumDescriptor {
+	return file_proxy_http_config_proto_enumTypes[1].Descriptor()
+}
+
+func (ClientConfig_Strategy) Type() protoreflect.EnumType {
+	return &file_proxy_http_config_proto_enumTypes[1]
+}
+
+func (x ClientConfig_Strategy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// HashHeader_Algorithm selects how HashHeader computes its header value.
+type HashHeader_Algorithm int32
+
+const (
+	HashHeader_UNKNOWN HashHeader_Algorithm = 0
+	// XT5 reproduces the legacy bd_x_t5_auth hash: a running
+	// index = (index*1318293 & 0x7FFFFFFF) + rune, folded into [0, 0x7FFFFFFF)
+	// and formatted as decimal.
+	HashHeader_XT5      HashHeader_Algorithm = 1
+	HashHeader_CRC32    HashHeader_Algorithm = 2
+	HashHeader_SHA1_HEX HashHeader_Algorithm = 3
+	HashHeader_HMAC     HashHeader_Algorithm = 4
+)
+
+// Enum value maps for HashHeader_Algorithm.
+var (
+	HashHeader_Algorithm_name = map[int32]string{
+		0: "UNKNOWN",
+		1: "XT5",
+		2: "CRC32",
+		3: "SHA1_HEX",
+		4: "HMAC",
+	}
+	HashHeader_Algorithm_value = map[string]int32{
+		"UNKNOWN":  0,
+		"XT5":      1,
+		"CRC32":    2,
+		"SHA1_HEX": 3,
+		"HMAC":     4,
+	}
+)
+
+func (x HashHeader_Algorithm) Enum() *HashHeader_Algorithm {
+	p := new(HashHeader_Algorithm)
+	*p = x
+	return p
+}
+
+func (x HashHeader_Algorithm) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (HashHeader_Algorithm) Descriptor() protoreflect.EnumDescriptor {
+	return file_proxy_http_config_proto_enumTypes[2].Descriptor()
+}
+
+func (HashHeader_Algorithm) Type() protoreflect.EnumType {
+	return &file_proxy_http_config_proto_enumTypes[2]
+}
+
+func (x HashHeader_Algorithm) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// HashHeader_Source picks whether the hash is computed over the proxy
+// destination host or the original request target.
+type HashHeader_Source int32
+
+const (
+	HashHeader_HOST   HashHeader_Source = 0
+	HashHeader_TARGET HashHeader_Source = 1
+)
+
+var (
+	HashHeader_Source_name = map[int32]string{
+		0: "HOST",
+		1: "TARGET",
+	}
+	HashHeader_Source_value = map[string]int32{
+		"HOST":   0,
+		"TARGET": 1,
+	}
+)
+
+func (x HashHeader_Source) Enum() *HashHeader_Source {
+	p := new(HashHeader_Source)
+	*p = x
+	return p
+}
+
+func (x HashHeader_Source) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (HashHeader_Source) Descriptor() protoreflect.EnumDescriptor {
+	return file_proxy_http_config_proto_enumTypes[3].Descriptor()
+}
+
+func (HashHeader_Source) Type() protoreflect.EnumType {
+	return &file_proxy_http_config_proto_enumTypes[3]
+}
+
+func (x HashHeader_Source) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+type ClientConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Server []*protocol.ServerEndpoint `protobuf:"bytes,1,rep,name=server,proto3" json:"server,omitempty"`
+	// ChainTag, when set, names another configured outbound (SOCKS/HTTP/SSH/
+	// VMess/...) that setUpHTTPTunnel dials through instead of calling
+	// dialer.Dial directly, so a client -> detour -> http-proxy -> target
+	// pipeline can be expressed without a custom transport.
+	ChainTag string `protobuf:"bytes,2,opt,name=chain_tag,json=chainTag,proto3" json:"chain_tag,omitempty"`
+	// Rewrite holds the request-rewrite rules evaluated, in order, against
+	// the CONNECT destination before the tunnel is established. It replaces
+	// the previous hard-coded cloudnproxy.baidu.com / 10.0.0.172 handling.
+	Rewrite []*RewriteRule `protobuf:"bytes,3,rep,name=rewrite,proto3" json:"rewrite,omitempty"`
+	// H2PoolPingIntervalSec is the interval, in seconds, at which the h2
+	// connection pool's janitor PINGs cached CONNECT tunnels to detect dead
+	// peers. 0 selects the default (30s).
+	H2PoolPingIntervalSec uint32 `protobuf:"varint,4,opt,name=h2_pool_ping_interval_sec,json=h2PoolPingIntervalSec,proto3" json:"h2_pool_ping_interval_sec,omitempty"`
+	// H2PoolMaxIdleSec evicts a cached h2 connection that has not been reused
+	// for this many seconds. 0 selects the default (5m); a negative value is
+	// not representable and is treated as 0.
+	H2PoolMaxIdleSec uint32 `protobuf:"varint,5,opt,name=h2_pool_max_idle_sec,json=h2PoolMaxIdleSec,proto3" json:"h2_pool_max_idle_sec,omitempty"`
+	// H2PoolMaxLifetimeSec evicts a cached h2 connection this many seconds
+	// after it was established, regardless of activity. 0 means unlimited.
+	H2PoolMaxLifetimeSec uint32 `protobuf:"varint,6,opt,name=h2_pool_max_lifetime_sec,json=h2PoolMaxLifetimeSec,proto3" json:"h2_pool_max_lifetime_sec,omitempty"`
+	// UdpMode selects how UDP destinations are relayed, since a plain HTTP
+	// CONNECT tunnel only carries TCP. Defaults to DISABLED, preserving the
+	// outbound's original UDP-rejecting behavior.
+	UdpMode ClientConfig_UdpMode `protobuf:"varint,7,opt,name=udp_mode,json=udpMode,proto3,enum=v2ray.core.proxy.http.ClientConfig_UdpMode" json:"udp_mode,omitempty"`
+	// Strategy selects how a destination is picked from Server on each
+	// connection. The default, ROUND_ROBIN, cycles through Server in order.
+	Strategy ClientConfig_Strategy `protobuf:"varint,8,opt,name=strategy,proto3,enum=v2ray.core.proxy.http.ClientConfig_Strategy" json:"strategy,omitempty"`
+	// ServerWeights holds the WEIGHTED_RANDOM selection weight for each entry
+	// of Server, aligned by index. A missing or zero entry defaults to 1.
+	ServerWeights []uint32 `protobuf:"varint,9,rep,packed,name=server_weights,json=serverWeights,proto3" json:"server_weights,omitempty"`
+	// FailureCooldownSec is how long, in seconds, a server is skipped by
+	// selection after a failed CONNECT handshake. 0 selects the default
+	// (10s).
+	FailureCooldownSec uint32 `protobuf:"varint,10,opt,name=failure_cooldown_sec,json=failureCooldownSec,proto3" json:"failure_cooldown_sec,omitempty"`
+}
+
+func (x *ClientConfig) Reset() {
+	*x = ClientConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proxy_http_config_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientConfig) ProtoMessage() {}
+
+func (x *ClientConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_proxy_http_config_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientConfig.ProtoReflect.Descriptor instead.
+func (*ClientConfig) Descriptor() ([]byte, []int) {
+	return file_proxy_http_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ClientConfig) GetServer() []*protocol.ServerEndpoint {
+	if x != nil {
+		return x.Server
+	}
+	return nil
+}
+
+func (x *ClientConfig) GetChainTag() string {
+	if x != nil {
+		return x.ChainTag
+	}
+	return ""
+}
+
+func (x *ClientConfig) GetRewrite() []*RewriteRule {
+	if x != nil {
+		return x.Rewrite
+	}
+	return nil
+}
+
+func (x *ClientConfig) GetH2PoolPingIntervalSec() uint32 {
+	if x != nil {
+		return x.H2PoolPingIntervalSec
+	}
+	return 0
+}
+
+func (x *ClientConfig) GetH2PoolMaxIdleSec() uint32 {
+	if x != nil {
+		return x.H2PoolMaxIdleSec
+	}
+	return 0
+}
+
+func (x *ClientConfig) GetH2PoolMaxLifetimeSec() uint32 {
+	if x != nil {
+		return x.H2PoolMaxLifetimeSec
+	}
+	return 0
+}
+
+func (x *ClientConfig) GetUdpMode() ClientConfig_UdpMode {
+	if x != nil {
+		return x.UdpMode
+	}
+	return ClientConfig_DISABLED
+}
+
+func (x *ClientConfig) GetStrategy() ClientConfig_Strategy {
+	if x != nil {
+		return x.Strategy
+	}
+	return ClientConfig_ROUND_ROBIN
+}
+
+func (x *ClientConfig) GetServerWeights() []uint32 {
+	if x != nil {
+		return x.ServerWeights
+	}
+	return nil
+}
+
+func (x *ClientConfig) GetFailureCooldownSec() uint32 {
+	if x != nil {
+		return x.FailureCooldownSec
+	}
+	return 0
+}
+
+// RewriteRule is one entry of the configurable request-rewrite subsystem.
+// Rules are evaluated in order; the first one whose MatchDestHost matches
+// (or that has an empty MatchDestHost, matching unconditionally) applies.
+type RewriteRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MatchDestHost string            `protobuf:"bytes,1,opt,name=match_dest_host,json=matchDestHost,proto3" json:"match_dest_host,omitempty"`
+	SetHeaders    map[string]string `protobuf:"bytes,2,rep,name=set_headers,json=setHeaders,proto3" json:"set_headers,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// OverrideHost may contain the literal placeholder "{target}", replaced
+	// with the real per-connection CONNECT target (host:port) before use, so
+	// a rule can smuggle the real destination past a host-based rewrite
+	// instead of discarding it.
+	OverrideHost string `protobuf:"bytes,3,opt,name=override_host,json=overrideHost,proto3" json:"override_host,omitempty"`
+	// OverrideUrlOpaque supports the same "{target}" placeholder as
+	// OverrideHost.
+	OverrideUrlOpaque string      `protobuf:"bytes,4,opt,name=override_url_opaque,json=overrideUrlOpaque,proto3" json:"override_url_opaque,omitempty"`
+	HashHeader        *HashHeader `protobuf:"bytes,5,opt,name=hash_header,json=hashHeader,proto3" json:"hash_header,omitempty"`
+}
+
+func (x *RewriteRule) Reset() {
+	*x = RewriteRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proxy_http_config_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RewriteRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RewriteRule) ProtoMessage() {}
+
+func (x *RewriteRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proxy_http_config_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RewriteRule.ProtoReflect.Descriptor instead.
+func (*RewriteRule) Descriptor() ([]byte, []int) {
+	return file_proxy_http_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RewriteRule) GetMatchDestHost() string {
+	if x != nil {
+		return x.MatchDestHost
+	}
+	return ""
+}
+
+func (x *RewriteRule) GetSetHeaders() map[string]string {
+	if x != nil {
+		return x.SetHeaders
+	}
+	return nil
+}
+
+func (x *RewriteRule) GetOverrideHost() string {
+	if x != nil {
+		return x.OverrideHost
+	}
+	return ""
+}
+
+func (x *RewriteRule) GetOverrideUrlOpaque() string {
+	if x != nil {
+		return x.OverrideUrlOpaque
+	}
+	return ""
+}
+
+func (x *RewriteRule) GetHashHeader() *HashHeader {
+	if x != nil {
+		return x.HashHeader
+	}
+	return nil
+}
+
+// HashHeader configures a single computed-hash header, e.g. the legacy
+// X-T5-Auth carrier-proxy quirk expressed as `algorithm: XT5`.
+type HashHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name      string               `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Algorithm HashHeader_Algorithm `protobuf:"varint,2,opt,name=algorithm,proto3,enum=v2ray.core.proxy.http.HashHeader_Algorithm" json:"algorithm,omitempty"`
+	Seed      string               `protobuf:"bytes,3,opt,name=seed,proto3" json:"seed,omitempty"`
+	Source    HashHeader_Source    `protobuf:"varint,4,opt,name=source,proto3,enum=v2ray.core.proxy.http.HashHeader_Source" json:"source,omitempty"`
+}
+
+func (x *HashHeader) Reset() {
+	*x = HashHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proxy_http_config_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HashHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashHeader) ProtoMessage() {}
+
+func (x *HashHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_proxy_http_config_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashHeader.ProtoReflect.Descriptor instead.
+func (*HashHeader) Descriptor() ([]byte, []int) {
+	return file_proxy_http_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HashHeader) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *HashHeader) GetAlgorithm() HashHeader_Algorithm {
+	if x != nil {
+		return x.Algorithm
+	}
+	return HashHeader_UNKNOWN
+}
+
+func (x *HashHeader) GetSeed() string {
+	if x != nil {
+		return x.Seed
+	}
+	return ""
+}
+
+func (x *HashHeader) GetSource() HashHeader_Source {
+	if x != nil {
+		return x.Source
+	}
+	return HashHeader_HOST
+}
+
+var File_proxy_http_config_proto protoreflect.FileDescriptor
+
+var file_proxy_http_config_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2f, 0x68, 0x74, 0x74, 0x70, 0x2f, 0x63, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x15, 0x76, 0x32, 0x72, 0x61, 0x79,
+	0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74, 0x70,
+	0x1a, 0x20, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x65, 0x78,
+	0x74, 0x2f, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x21, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x5f, 0x73, 0x70, 0x65, 0x63, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xf7, 0x05, 0x0a, 0x0c, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x42, 0x0a, 0x06, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63,
+	0x6f, 0x72, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x2e, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x45, 0x6e, 0x64, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x52, 0x06, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x68,
+	0x61, 0x69, 0x6e, 0x5f, 0x74, 0x61, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x54, 0x61, 0x67, 0x12, 0x3c, 0x0a, 0x07, 0x72, 0x65, 0x77, 0x72, 0x69,
+	0x74, 0x65, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79,
+	0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74, 0x70,
+	0x2e, 0x52, 0x65, 0x77, 0x72, 0x69, 0x74, 0x65, 0x52, 0x75, 0x6c, 0x65, 0x52, 0x07, 0x72, 0x65,
+	0x77, 0x72, 0x69, 0x74, 0x65, 0x12, 0x38, 0x0a, 0x19, 0x68, 0x32, 0x5f, 0x70, 0x6f, 0x6f, 0x6c,
+	0x5f, 0x70, 0x69, 0x6e, 0x67, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x73,
+	0x65, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x15, 0x68, 0x32, 0x50, 0x6f, 0x6f, 0x6c,
+	0x50, 0x69, 0x6e, 0x67, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x53, 0x65, 0x63, 0x12,
+	0x2e, 0x0a, 0x14, 0x68, 0x32, 0x5f, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x69,
+	0x64, 0x6c, 0x65, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x10, 0x68,
+	0x32, 0x50, 0x6f, 0x6f, 0x6c, 0x4d, 0x61, 0x78, 0x49, 0x64, 0x6c, 0x65, 0x53, 0x65, 0x63, 0x12,
+	0x36, 0x0a, 0x18, 0x68, 0x32, 0x5f, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x6d, 0x61, 0x78, 0x5f, 0x6c,
+	0x69, 0x66, 0x65, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x14, 0x68, 0x32, 0x50, 0x6f, 0x6f, 0x6c, 0x4d, 0x61, 0x78, 0x4c, 0x69, 0x66, 0x65,
+	0x74, 0x69, 0x6d, 0x65, 0x53, 0x65, 0x63, 0x12, 0x46, 0x0a, 0x08, 0x75, 0x64, 0x70, 0x5f, 0x6d,
+	0x6f, 0x64, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2b, 0x2e, 0x76, 0x32, 0x72, 0x61,
+	0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74,
+	0x70, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x55,
+	0x64, 0x70, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x07, 0x75, 0x64, 0x70, 0x4d, 0x6f, 0x64, 0x65, 0x12,
+	0x48, 0x0a, 0x08, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x2c, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74, 0x70, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74,
+	0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x52,
+	0x08, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x5f, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28,
+	0x0d, 0x52, 0x0d, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x73,
+	0x12, 0x30, 0x0a, 0x14, 0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x5f, 0x63, 0x6f, 0x6f, 0x6c,
+	0x64, 0x6f, 0x77, 0x6e, 0x5f, 0x73, 0x65, 0x63, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x12,
+	0x66, 0x61, 0x69, 0x6c, 0x75, 0x72, 0x65, 0x43, 0x6f, 0x6f, 0x6c, 0x64, 0x6f, 0x77, 0x6e, 0x53,
+	0x65, 0x63, 0x22, 0x39, 0x0a, 0x07, 0x55, 0x64, 0x70, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x0c, 0x0a,
+	0x08, 0x44, 0x49, 0x53, 0x41, 0x42, 0x4c, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x52,
+	0x46, 0x43, 0x39, 0x32, 0x39, 0x38, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x4c, 0x45, 0x4e, 0x47,
+	0x54, 0x48, 0x5f, 0x50, 0x52, 0x45, 0x46, 0x49, 0x58, 0x45, 0x44, 0x10, 0x02, 0x22, 0x66, 0x0a,
+	0x08, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x0f, 0x0a, 0x0b, 0x52, 0x4f, 0x55,
+	0x4e, 0x44, 0x5f, 0x52, 0x4f, 0x42, 0x49, 0x4e, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x52, 0x41,
+	0x4e, 0x44, 0x4f, 0x4d, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x4c, 0x45, 0x41, 0x53, 0x54, 0x5f,
+	0x4c, 0x41, 0x54, 0x45, 0x4e, 0x43, 0x59, 0x10, 0x02, 0x12, 0x15, 0x0a, 0x11, 0x4c, 0x45, 0x41,
+	0x53, 0x54, 0x5f, 0x43, 0x4f, 0x4e, 0x43, 0x55, 0x52, 0x52, 0x45, 0x4e, 0x43, 0x59, 0x10, 0x03,
+	0x12, 0x13, 0x0a, 0x0f, 0x57, 0x45, 0x49, 0x47, 0x48, 0x54, 0x45, 0x44, 0x5f, 0x52, 0x41, 0x4e,
+	0x44, 0x4f, 0x4d, 0x10, 0x04, 0x3a, 0x18, 0x82, 0xb5, 0x18, 0x0a, 0x0a, 0x08, 0x6f, 0x75, 0x74,
+	0x62, 0x6f, 0x75, 0x6e, 0x64, 0x82, 0xb5, 0x18, 0x06, 0x12, 0x04, 0x68, 0x74, 0x74, 0x70, 0x22,
+	0xe2, 0x02, 0x0a, 0x0b, 0x52, 0x65, 0x77, 0x72, 0x69, 0x74, 0x65, 0x52, 0x75, 0x6c, 0x65, 0x12,
+	0x26, 0x0a, 0x0f, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x64, 0x65, 0x73, 0x74, 0x5f, 0x68, 0x6f,
+	0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x44,
+	0x65, 0x73, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x12, 0x53, 0x0a, 0x0b, 0x73, 0x65, 0x74, 0x5f, 0x68,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x32, 0x2e, 0x76,
+	0x32, 0x72, 0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e,
+	0x68, 0x74, 0x74, 0x70, 0x2e, 0x52, 0x65, 0x77, 0x72, 0x69, 0x74, 0x65, 0x52, 0x75, 0x6c, 0x65,
+	0x2e, 0x53, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x0a, 0x73, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x23, 0x0a, 0x0d,
+	0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x48, 0x6f, 0x73,
+	0x74, 0x12, 0x2e, 0x0a, 0x13, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x5f, 0x75, 0x72,
+	0x6c, 0x5f, 0x6f, 0x70, 0x61, 0x71, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x11,
+	0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x55, 0x72, 0x6c, 0x4f, 0x70, 0x61, 0x71, 0x75,
+	0x65, 0x12, 0x42, 0x0a, 0x0b, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63,
+	0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74, 0x70, 0x2e, 0x48,
+	0x61, 0x73, 0x68, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x0a, 0x68, 0x61, 0x73, 0x68, 0x48,
+	0x65, 0x61, 0x64, 0x65, 0x72, 0x1a, 0x3d, 0x0a, 0x0f, 0x53, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x22, 0xa7, 0x02, 0x0a, 0x0a, 0x48, 0x61, 0x73, 0x68, 0x48, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x49, 0x0a, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72,
+	0x69, 0x74, 0x68, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x2b, 0x2e, 0x76, 0x32, 0x72,
+	0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74,
+	0x74, 0x70, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2e, 0x41, 0x6c,
+	0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x52, 0x09, 0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74,
+	0x68, 0x6d, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x65, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x73, 0x65, 0x65, 0x64, 0x12, 0x40, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x28, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63,
+	0x6f, 0x72, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74, 0x70, 0x2e, 0x48,
+	0x61, 0x73, 0x68, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x2e, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x22, 0x44, 0x0a, 0x09, 0x41, 0x6c, 0x67, 0x6f,
+	0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e,
+	0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x58, 0x54, 0x35, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x43,
+	0x52, 0x43, 0x33, 0x32, 0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x31, 0x5f, 0x48,
+	0x45, 0x58, 0x10, 0x03, 0x12, 0x08, 0x0a, 0x04, 0x48, 0x4d, 0x41, 0x43, 0x10, 0x04, 0x22, 0x1e,
+	0x0a, 0x06, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x08, 0x0a, 0x04, 0x48, 0x4f, 0x53, 0x54,
+	0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x54, 0x41, 0x52, 0x47, 0x45, 0x54, 0x10, 0x01, 0x42, 0x60,
+	0x0a, 0x19, 0x63, 0x6f, 0x6d, 0x2e, 0x76, 0x32, 0x72, 0x61, 0x79, 0x2e, 0x63, 0x6f, 0x72, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x68, 0x74, 0x74, 0x70, 0x50, 0x01, 0x5a, 0x29, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x76, 0x32, 0x66, 0x6c, 0x79, 0x2f,
+	0x76, 0x32, 0x72, 0x61, 0x79, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x76, 0x35, 0x2f, 0x70, 0x72,
+	0x6f, 0x78, 0x79, 0x2f, 0x68, 0x74, 0x74, 0x70, 0xaa, 0x02, 0x15, 0x56, 0x32, 0x52, 0x61, 0x79,
+	0x2e, 0x43, 0x6f, 0x72, 0x65, 0x2e, 0x50, 0x72, 0x6f, 0x78, 0x79, 0x2e, 0x48, 0x74, 0x74, 0x70,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proxy_http_config_proto_rawDescOnce sync.Once
+	file_proxy_http_config_proto_rawDescData = file_proxy_http_config_proto_rawDesc
+)
+
+func file_proxy_http_config_proto_rawDescGZIP() []byte {
+	file_proxy_http_config_proto_rawDescOnce.Do(func() {
+		file_proxy_http_config_proto_rawDescData = protoimpl.X.CompressGZIP(file_proxy_http_config_proto_rawDescData)
+	})
+	return file_proxy_http_config_proto_rawDescData
+}
+
+var file_proxy_http_config_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_proxy_http_config_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proxy_http_config_proto_goTypes = []interface{}{
+	(ClientConfig_UdpMode)(0),       // 0: v2ray.core.proxy.http.ClientConfig.UdpMode
+	(ClientConfig_Strategy)(0),      // 1: v2ray.core.proxy.http.ClientConfig.Strategy
+	(HashHeader_Algorithm)(0),       // 2: v2ray.core.proxy.http.HashHeader.Algorithm
+	(HashHeader_Source)(0),          // 3: v2ray.core.proxy.http.HashHeader.Source
+	(*ClientConfig)(nil),            // 4: v2ray.core.proxy.http.ClientConfig
+	(*RewriteRule)(nil),             // 5: v2ray.core.proxy.http.RewriteRule
+	(*HashHeader)(nil),              // 6: v2ray.core.proxy.http.HashHeader
+	nil,                             // 7: v2ray.core.proxy.http.RewriteRule.SetHeadersEntry
+	(*protocol.ServerEndpoint)(nil), // 8: v2ray.core.common.protocol.ServerEndpoint
+}
+var file_proxy_http_config_proto_depIdxs = []int32{
+	8, // 0: v2ray.core.proxy.http.ClientConfig.server:type_name -> v2ray.core.common.protocol.ServerEndpoint
+	5, // 1: v2ray.core.proxy.http.ClientConfig.rewrite:type_name -> v2ray.core.proxy.http.RewriteRule
+	0, // 2: v2ray.core.proxy.http.ClientConfig.udp_mode:type_name -> v2ray.core.proxy.http.ClientConfig.UdpMode
+	1, // 3: v2ray.core.proxy.http.ClientConfig.strategy:type_name -> v2ray.core.proxy.http.ClientConfig.Strategy
+	7, // 4: v2ray.core.proxy.http.RewriteRule.set_headers:type_name -> v2ray.core.proxy.http.RewriteRule.SetHeadersEntry
+	6, // 5: v2ray.core.proxy.http.RewriteRule.hash_header:type_name -> v2ray.core.proxy.http.HashHeader
+	2, // 6: v2ray.core.proxy.http.HashHeader.algorithm:type_name -> v2ray.core.proxy.http.HashHeader.Algorithm
+	3, // 7: v2ray.core.proxy.http.HashHeader.source:type_name -> v2ray.core.proxy.http.HashHeader.Source
+	8, // [8:8] is the sub-list for method output_type
+	8, // [8:8] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_proxy_http_config_proto_init() }
+func file_proxy_http_config_proto_init() {
+	if File_proxy_http_config_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proxy_http_config_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proxy_http_config_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RewriteRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proxy_http_config_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HashHeader); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proxy_http_config_proto_rawDesc,
+			NumEnums:      4,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proxy_http_config_proto_goTypes,
+		DependencyIndexes: file_proxy_http_config_proto_depIdxs,
+		EnumInfos:         file_proxy_http_config_proto_enumTypes,
+		MessageInfos:      file_proxy_http_config_proto_msgTypes,
+	}.Build()
+	File_proxy_http_config_proto = out.File
+	file_proxy_http_config_proto_rawDesc = nil
+	file_proxy_http_config_proto_goTypes = nil
+	file_proxy_http_config_proto_depIdxs = nil
+}
@@ -0,0 +1,297 @@
+package ssh
+
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	gonet "net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/common/signal"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+// Client is an outbound handler for the SSH protocol. It dials the configured
+// SSH server once per destination, reuses the resulting transport across
+// concurrent Process calls, and opens a direct-tcpip channel for each one.
+type Client struct {
+	config        *Config
+	policyManager policy.Manager
+}
+
+// NewClient creates a new SSH client based on the given config.
+func NewClient(ctx context.Context, config *Config) (*Client, error) {
+	if config.Address == nil {
+		return nil, newError("SSH server address is not specified")
+	}
+
+	v := core.MustFromContext(ctx)
+	return &Client{
+		config:        config,
+		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+	}, nil
+}
+
+// sshConnEntry is a pooled, reference-counted SSH transport to a single
+// server. It is shared by all Process calls using the same sshPoolKey.
+// invalid marks an entry that invalidate() has condemned: it is removed from
+// the pool immediately but its client is only closed once refCount (the
+// number of Process calls still holding it) drops to zero, so one call's
+// failure cannot sever a transport that other concurrent calls are using.
+type sshConnEntry struct {
+	client   *ssh.Client
+	rawConn  internet.Connection
+	refCount int
+	invalid  bool
+}
+
+// sshPoolKey scopes the transport pool by destination *and* SSH identity, so
+// two differently configured outbounds that happen to point at the same
+// host:port never share one authenticated connection.
+type sshPoolKey struct {
+	dest     net.Destination
+	identity [sha256.Size]byte
+}
+
+// poolKey derives this Client's pool key: dest plus a hash of every
+// credential that determines which account the resulting SSH session
+// authenticates as.
+func (c *Client) poolKey(dest net.Destination) sshPoolKey {
+	h := sha256.New()
+	h.Write([]byte(c.config.User))
+	h.Write([]byte{0})
+	h.Write([]byte(c.config.Password))
+	h.Write([]byte{0})
+	h.Write([]byte(c.config.PrivateKey))
+	h.Write([]byte{0})
+	h.Write([]byte(c.config.PublicKey))
+
+	key := sshPoolKey{dest: dest}
+	copy(key.identity[:], h.Sum(nil))
+	return key
+}
+
+var (
+	cachedSSHMutex sync.Mutex
+	cachedSSHConns map[sshPoolKey]*sshConnEntry
+)
+
+// Process implements proxy.Outbound.Process. It reuses (or creates) an SSH
+// transport to the configured server, then opens a direct-tcpip channel to
+// outbound.Target and pipes it to link.Reader/link.Writer.
+func (c *Client) Process(ctx context.Context, link *transport.Link, dialer internet.Dialer) error {
+	outbound := session.OutboundFromContext(ctx)
+	if outbound == nil || !outbound.Target.IsValid() {
+		return newError("target not specified.")
+	}
+	target := outbound.Target
+
+	if target.Network == net.Network_UDP {
+		return newError("UDP is not supported by SSH outbound")
+	}
+
+	dest := net.Destination{
+		Network: net.Network_TCP,
+		Address: c.config.Address.AsAddress(),
+		Port:    net.Port(c.config.Port),
+	}
+
+	key := c.poolKey(dest)
+
+	entry, err := c.getClient(ctx, key, dialer)
+	if err != nil {
+		return newError("failed to establish SSH transport to ", dest).Base(err)
+	}
+	defer c.releaseClient(key, entry)
+
+	channelConn, err := entry.client.Dial("tcp", target.NetAddr())
+	if err != nil {
+		c.invalidate(key, entry)
+		return newError("failed to open direct-tcpip channel to ", target).Base(err)
+	}
+	defer channelConn.Close()
+
+	p := c.policyManager.ForLevel(c.config.UserLevel)
+
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, p.Timeouts.ConnectionIdle)
+
+	requestFunc := func() error {
+		defer timer.SetTimeout(p.Timeouts.DownlinkOnly)
+		return buf.Copy(link.Reader, buf.NewWriter(channelConn), buf.UpdateActivity(timer))
+	}
+	responseFunc := func() error {
+		defer timer.SetTimeout(p.Timeouts.UplinkOnly)
+		return buf.Copy(buf.NewReader(channelConn), link.Writer, buf.UpdateActivity(timer))
+	}
+
+	responseDonePost := task.OnSuccess(responseFunc, task.Close(link.Writer))
+	if err := task.Run(ctx, requestFunc, responseDonePost); err != nil {
+		return newError("connection ends").Base(err)
+	}
+
+	return nil
+}
+
+// getClient returns a pooled, reference-counted SSH transport for key,
+// dialing and negotiating a new one if none is cached (or the cached one has
+// died or been invalidated). Callers must pair every successful call with a
+// matching releaseClient.
+func (c *Client) getClient(ctx context.Context, key sshPoolKey, dialer internet.Dialer) (*sshConnEntry, error) {
+	cachedSSHMutex.Lock()
+	if entry, found := cachedSSHConns[key]; found && !entry.invalid {
+		entry.refCount++
+		cachedSSHMutex.Unlock()
+		return entry, nil
+	}
+	cachedSSHMutex.Unlock()
+
+	rawConn, err := dialer.Dial(ctx, key.dest)
+	if err != nil {
+		return nil, err
+	}
+
+	clientConfig, err := c.buildSSHConfig()
+	if err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(rawConn, key.dest.NetAddr(), clientConfig)
+	if err != nil {
+		rawConn.Close()
+		return nil, newError("failed to negotiate SSH transport").Base(err)
+	}
+
+	client := ssh.NewClient(sshConn, chans, reqs)
+	entry := &sshConnEntry{client: client, rawConn: rawConn, refCount: 1}
+
+	cachedSSHMutex.Lock()
+	if cachedSSHConns == nil {
+		cachedSSHConns = make(map[sshPoolKey]*sshConnEntry)
+	}
+	cachedSSHConns[key] = entry
+	cachedSSHMutex.Unlock()
+
+	// Evict the pooled entry once the underlying connection dies, so the
+	// next Process call dials a fresh transport instead of reusing a dead one.
+	go func() {
+		client.Wait() // nolint: errcheck
+		cachedSSHMutex.Lock()
+		if cachedSSHConns[key] == entry {
+			delete(cachedSSHConns, key)
+		}
+		cachedSSHMutex.Unlock()
+	}()
+
+	return entry, nil
+}
+
+// releaseClient drops this Process call's reference to entry. If entry was
+// condemned by invalidate and this was the last reference, the transport is
+// torn down now; otherwise it stays cached (or stays alive for whichever
+// Process calls still hold it) for reuse.
+func (c *Client) releaseClient(key sshPoolKey, entry *sshConnEntry) {
+	cachedSSHMutex.Lock()
+	entry.refCount--
+	shouldClose := entry.invalid && entry.refCount <= 0
+	cachedSSHMutex.Unlock()
+
+	if shouldClose {
+		entry.client.Close()
+	}
+}
+
+// invalidate condemns entry: it is removed from the pool immediately, so no
+// later Process call can acquire it, but entry.client is only closed once
+// every Process call currently holding a reference to it (including this
+// one, via its own deferred releaseClient) has released it. This keeps one
+// call's failure (e.g. a channel dial error) from severing a transport that
+// other concurrent calls are still using successfully.
+func (c *Client) invalidate(key sshPoolKey, entry *sshConnEntry) {
+	cachedSSHMutex.Lock()
+	entry.invalid = true
+	if cachedSSHConns[key] == entry {
+		delete(cachedSSHConns, key)
+	}
+	shouldClose := entry.refCount <= 0
+	cachedSSHMutex.Unlock()
+
+	if shouldClose {
+		entry.client.Close()
+	}
+}
+
+func (c *Client) buildSSHConfig() (*ssh.ClientConfig, error) {
+	var authMethods []ssh.AuthMethod
+	if c.config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(c.config.Password))
+	}
+	if c.config.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(c.config.PrivateKey))
+		if err != nil {
+			return nil, newError("failed to parse configured SSH private key").Base(err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	if len(authMethods) == 0 {
+		return nil, newError("either password or private_key must be configured")
+	}
+
+	hostKeyCallback, err := c.buildHostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	clientVersion := c.config.ClientVersion
+	if clientVersion == "" {
+		clientVersion = "SSH-2.0-v2ray"
+	}
+
+	return &ssh.ClientConfig{
+		User:              c.config.User,
+		Auth:              authMethods,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: c.config.HostKeyAlgorithms,
+		ClientVersion:     clientVersion,
+	}, nil
+}
+
+// buildHostKeyCallback verifies the server host key against the configured
+// PublicKey, rejecting the handshake on any mismatch. If no PublicKey is
+// configured, the host key is not verified.
+func (c *Client) buildHostKeyCallback() (ssh.HostKeyCallback, error) {
+	if c.config.PublicKey == "" {
+		return ssh.InsecureIgnoreHostKey(), nil // nolint: gosec
+	}
+
+	expected, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.config.PublicKey))
+	if err != nil {
+		return nil, newError("failed to parse configured SSH host public key").Base(err)
+	}
+
+	return func(hostname string, remote gonet.Addr, key ssh.PublicKey) error {
+		if !bytes.Equal(key.Marshal(), expected.Marshal()) {
+			return newError("SSH host key mismatch for ", hostname)
+		}
+		return nil
+	}, nil
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		return NewClient(ctx, config.(*Config))
+	}))
+}
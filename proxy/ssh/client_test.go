@@ -0,0 +1,287 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	gonet "net"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+func testDest(host string) net.Destination {
+	return net.Destination{
+		Network: net.Network_TCP,
+		Address: net.ParseAddress(host),
+		Port:    net.Port(22),
+	}
+}
+
+func TestPoolKeyScopedByDestination(t *testing.T) {
+	c := &Client{config: &Config{User: "alice", Password: "hunter2"}}
+	a := c.poolKey(testDest("a.example.com"))
+	b := c.poolKey(testDest("b.example.com"))
+	if a == b {
+		t.Fatalf("poolKey did not vary with destination: both produced %v", a)
+	}
+}
+
+func TestPoolKeyScopedByIdentity(t *testing.T) {
+	dest := testDest("example.com")
+	alice := (&Client{config: &Config{User: "alice", Password: "hunter2"}}).poolKey(dest)
+	bob := (&Client{config: &Config{User: "bob", Password: "hunter2"}}).poolKey(dest)
+	alicePw2 := (&Client{config: &Config{User: "alice", Password: "different"}}).poolKey(dest)
+
+	if alice == bob {
+		t.Errorf("poolKey did not vary with User: two different users pointed at the same dest produced the same key (%v), so they would share one authenticated SSH session", alice)
+	}
+	if alice == alicePw2 {
+		t.Errorf("poolKey did not vary with Password: same user, different password, produced the same key (%v)", alice)
+	}
+}
+
+func TestPoolKeyDeterministic(t *testing.T) {
+	dest := testDest("example.com")
+	c := &Client{config: &Config{User: "alice", Password: "hunter2"}}
+	if c.poolKey(dest) != c.poolKey(dest) {
+		t.Fatalf("poolKey is not deterministic for identical config and destination")
+	}
+}
+
+// fakeSSHConn is a minimal ssh.Conn that never touches the network, so
+// releaseClient/invalidate's refcount-gated teardown can be exercised without
+// a real SSH handshake.
+type fakeSSHConn struct {
+	closed chan struct{}
+}
+
+func newFakeSSHConn() *fakeSSHConn {
+	return &fakeSSHConn{closed: make(chan struct{})}
+}
+
+func (f *fakeSSHConn) User() string                                           { return "" }
+func (f *fakeSSHConn) SessionID() []byte                                      { return nil }
+func (f *fakeSSHConn) ClientVersion() []byte                                  { return nil }
+func (f *fakeSSHConn) ServerVersion() []byte                                  { return nil }
+func (f *fakeSSHConn) RemoteAddr() gonet.Addr                                 { return nil }
+func (f *fakeSSHConn) LocalAddr() gonet.Addr                                  { return nil }
+func (f *fakeSSHConn) SendRequest(string, bool, []byte) (bool, []byte, error) { return false, nil, nil }
+func (f *fakeSSHConn) OpenChannel(string, []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	return nil, nil, newError("fakeSSHConn does not support opening channels")
+}
+func (f *fakeSSHConn) Close() error {
+	select {
+	case <-f.closed:
+	default:
+		close(f.closed)
+	}
+	return nil
+}
+func (f *fakeSSHConn) Wait() error {
+	<-f.closed
+	return nil
+}
+
+func (f *fakeSSHConn) isClosed() bool {
+	select {
+	case <-f.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func newTestEntry() (*sshConnEntry, *fakeSSHConn) {
+	conn := newFakeSSHConn()
+	chans := make(chan ssh.NewChannel)
+	reqs := make(chan *ssh.Request)
+	close(chans)
+	close(reqs)
+	client := ssh.NewClient(conn, chans, reqs)
+	return &sshConnEntry{client: client, refCount: 1}, conn
+}
+
+func TestReleaseClientKeepsSharedEntryOpen(t *testing.T) {
+	c := &Client{}
+	key := sshPoolKey{dest: testDest("example.com")}
+	entry, conn := newTestEntry()
+	entry.refCount = 2 // two concurrent Process calls hold this entry.
+
+	c.releaseClient(key, entry)
+	if conn.isClosed() {
+		t.Fatalf("releaseClient closed the transport while another caller still held a reference")
+	}
+	if entry.refCount != 1 {
+		t.Errorf("releaseClient left refCount = %d, want 1", entry.refCount)
+	}
+}
+
+func TestReleaseClientDoesNotCloseValidEntry(t *testing.T) {
+	c := &Client{}
+	key := sshPoolKey{dest: testDest("example.com")}
+	entry, conn := newTestEntry()
+	entry.refCount = 1
+
+	c.releaseClient(key, entry)
+	if conn.isClosed() {
+		t.Fatalf("releaseClient closed a still-valid (non-invalidated) pooled entry")
+	}
+}
+
+func TestInvalidateDefersCloseUntilLastReference(t *testing.T) {
+	c := &Client{}
+	key := sshPoolKey{dest: testDest("example.com")}
+	entry, conn := newTestEntry()
+	entry.refCount = 2 // a second, concurrent Process call still holds this entry.
+
+	cachedSSHMutex.Lock()
+	cachedSSHConns = map[sshPoolKey]*sshConnEntry{key: entry}
+	cachedSSHMutex.Unlock()
+
+	c.invalidate(key, entry)
+	if conn.isClosed() {
+		t.Fatalf("invalidate closed the transport while a concurrent Process call still held a reference")
+	}
+	if !entry.invalid {
+		t.Fatalf("invalidate did not mark the entry invalid")
+	}
+	cachedSSHMutex.Lock()
+	_, stillCached := cachedSSHConns[key]
+	cachedSSHMutex.Unlock()
+	if stillCached {
+		t.Errorf("invalidate left a condemned entry reachable via the pool map")
+	}
+
+	// The other Process call finishes and releases its reference; the
+	// invalidating call still holds its own, so the transport stays open.
+	c.releaseClient(key, entry)
+	if conn.isClosed() {
+		t.Fatalf("releaseClient closed the transport while the invalidating call still held a reference")
+	}
+
+	// The invalidating call's own deferred releaseClient drops the last
+	// reference; only now should the transport actually close.
+	c.releaseClient(key, entry)
+	if !conn.isClosed() {
+		t.Fatalf("releaseClient did not close the transport once the last reference to an invalidated entry was released")
+	}
+}
+
+func TestInvalidateClosesOnSoleOwnerRelease(t *testing.T) {
+	c := &Client{}
+	key := sshPoolKey{dest: testDest("example.com")}
+	entry, conn := newTestEntry()
+	entry.refCount = 1 // this call is the only holder.
+
+	c.invalidate(key, entry)
+	// The invalidating call's own deferred releaseClient drops the only
+	// reference.
+	c.releaseClient(key, entry)
+	if !conn.isClosed() {
+		t.Fatalf("the transport was not closed once the invalidating call, its only reference, released it")
+	}
+}
+
+func TestBuildSSHConfigRequiresAuthMethod(t *testing.T) {
+	c := &Client{config: &Config{User: "alice"}}
+	if _, err := c.buildSSHConfig(); err == nil {
+		t.Fatalf("buildSSHConfig did not error with neither Password nor PrivateKey set")
+	}
+}
+
+func TestBuildSSHConfigPassword(t *testing.T) {
+	c := &Client{config: &Config{User: "alice", Password: "hunter2"}}
+	cfg, err := c.buildSSHConfig()
+	if err != nil {
+		t.Fatalf("buildSSHConfig returned error: %v", err)
+	}
+	if cfg.User != "alice" {
+		t.Errorf("buildSSHConfig User = %q, want %q", cfg.User, "alice")
+	}
+	if len(cfg.Auth) != 1 {
+		t.Errorf("buildSSHConfig produced %d auth methods, want 1", len(cfg.Auth))
+	}
+	if cfg.ClientVersion != "SSH-2.0-v2ray" {
+		t.Errorf("buildSSHConfig default ClientVersion = %q, want %q", cfg.ClientVersion, "SSH-2.0-v2ray")
+	}
+}
+
+func TestBuildSSHConfigRejectsInvalidPrivateKey(t *testing.T) {
+	c := &Client{config: &Config{User: "alice", PrivateKey: "not a key"}}
+	if _, err := c.buildSSHConfig(); err == nil {
+		t.Fatalf("buildSSHConfig did not error on a malformed PrivateKey")
+	}
+}
+
+func generateTestKeyPair(t *testing.T) (privateKeyPEM string, authorizedKey string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	privateKeyPEM = string(pem.EncodeToMemory(block))
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("ssh.NewSignerFromKey: %v", err)
+	}
+	authorizedKey = string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	return privateKeyPEM, authorizedKey
+}
+
+func TestBuildHostKeyCallbackUnconfiguredAcceptsAnyKey(t *testing.T) {
+	c := &Client{config: &Config{}}
+	callback, err := c.buildHostKeyCallback()
+	if err != nil {
+		t.Fatalf("buildHostKeyCallback returned error: %v", err)
+	}
+	_, authorizedKey := generateTestKeyPair(t)
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		t.Fatalf("ssh.ParseAuthorizedKey: %v", err)
+	}
+	if err := callback("example.com:22", nil, pub); err != nil {
+		t.Errorf("unconfigured host key callback rejected a key: %v", err)
+	}
+}
+
+func TestBuildHostKeyCallbackRejectsMismatch(t *testing.T) {
+	_, expectedAuthorizedKey := generateTestKeyPair(t)
+	c := &Client{config: &Config{PublicKey: expectedAuthorizedKey}}
+	callback, err := c.buildHostKeyCallback()
+	if err != nil {
+		t.Fatalf("buildHostKeyCallback returned error: %v", err)
+	}
+
+	_, otherAuthorizedKey := generateTestKeyPair(t)
+	other, _, _, _, err := ssh.ParseAuthorizedKey([]byte(otherAuthorizedKey))
+	if err != nil {
+		t.Fatalf("ssh.ParseAuthorizedKey: %v", err)
+	}
+	if err := callback("example.com:22", nil, other); err == nil {
+		t.Fatalf("host key callback accepted a key that does not match the configured PublicKey")
+	}
+}
+
+func TestBuildHostKeyCallbackAcceptsMatch(t *testing.T) {
+	expectedPEM, expectedAuthorizedKey := generateTestKeyPair(t)
+	_ = expectedPEM
+	c := &Client{config: &Config{PublicKey: expectedAuthorizedKey}}
+	callback, err := c.buildHostKeyCallback()
+	if err != nil {
+		t.Fatalf("buildHostKeyCallback returned error: %v", err)
+	}
+
+	expected, _, _, _, err := ssh.ParseAuthorizedKey([]byte(expectedAuthorizedKey))
+	if err != nil {
+		t.Fatalf("ssh.ParseAuthorizedKey: %v", err)
+	}
+	if err := callback("example.com:22", nil, expected); err != nil {
+		t.Errorf("host key callback rejected the configured key: %v", err)
+	}
+}